@@ -0,0 +1,54 @@
+package core
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultAssetConcurrency 默认同时下载的图片和附件数量
+const DefaultAssetConcurrency = 4
+
+// AssetResult 单个图片或附件的下载结果
+type AssetResult struct {
+	Token string
+	Path  string // 保存路径，与 DownloadImageRaw 返回的一致
+	Data  []byte
+	Err   error
+}
+
+// DownloadAssets 并发下载图片和附件，同时进行的下载不超过 concurrency 个
+// 重复的 token 只下载一次，结果按照 token 首次出现的顺序返回，单个下载失败不影响其他下载
+// 请求仍然经过客户端的限流器，并发数只限制同时等待响应的请求数量
+func (c *Client) DownloadAssets(ctx context.Context, tokens []string, dir string, concurrency int) []*AssetResult {
+	if concurrency <= 0 {
+		concurrency = DefaultAssetConcurrency
+	}
+
+	var results []*AssetResult
+	seen := map[string]bool{}
+	for _, token := range tokens {
+		if token == "" || seen[token] {
+			continue
+		}
+		seen[token] = true
+		results = append(results, &AssetResult{Token: token})
+	}
+
+	jobs := make(chan *AssetResult)
+	var wg sync.WaitGroup
+	for i := 0; i < min(concurrency, len(results)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for result := range jobs {
+				result.Path, result.Data, result.Err = c.DownloadImageRaw(ctx, result.Token, dir)
+			}
+		}()
+	}
+	for _, result := range results {
+		jobs <- result
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
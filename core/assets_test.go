@@ -0,0 +1,68 @@
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadAssets(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/tenant_access_token/internal") {
+			fmt.Fprint(w, `{"code":0,"msg":"ok","tenant_access_token":"t-g1044abcdefghijk","expire":7200}`)
+			return
+		}
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		token := path.Base(path.Dir(r.URL.Path))
+		mu.Lock()
+		requests[token]++
+		mu.Unlock()
+		if token == "bad" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"code":1061003,"msg":"not found"}`)
+			return
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.png"`, token))
+		fmt.Fprint(w, "image-"+token)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, core.WithRetryPolicy(core.RetryPolicy{}))
+	results := client.DownloadAssets(context.Background(), []string{"a", "b", "a", "bad", "c"}, "img", 2)
+
+	var tokens []string
+	for _, result := range results {
+		tokens = append(tokens, result.Token)
+	}
+	assert.Equal(t, []string{"a", "b", "bad", "c"}, tokens)
+	assert.Equal(t, map[string]int{"a": 1, "b": 1, "bad": 1, "c": 1}, requests)
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxInFlight), int32(2))
+
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "img/a.png", results[0].Path)
+	assert.Equal(t, []byte("image-a"), results[0].Data)
+	assert.ErrorIs(t, results[2].Err, core.ErrNotFound)
+	assert.NoError(t, results[3].Err)
+}
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// AssetManifestFile 共享图片库中记录图片和文档引用关系的文件
+const AssetManifestFile = "manifest.json"
+
+// AssetManifest 记录图片 token 对应的文件，以及每个文档引用了哪些文件
+type AssetManifest struct {
+	Tokens    map[string]string   `json:"tokens"`    // 图片 token → 文件名
+	Documents map[string][]string `json:"documents"` // 文档 token → 引用的文件名
+}
+
+// AssetStore 多个文档共享的图片库，文件按照内容的哈希值命名，相同的图片只保存一份
+type AssetStore struct {
+	Dir string
+
+	mu       sync.Mutex
+	manifest *AssetManifest
+	// 已经保存但还没有文档引用的文件，垃圾回收时跳过，避免删除正在导出的文档的图片
+	pending map[string]bool
+}
+
+var assetStores = struct {
+	sync.Mutex
+	stores map[string]*AssetStore
+}{stores: map[string]*AssetStore{}}
+
+// OpenAssetStore 打开目录下的共享图片库，同一目录在进程内共享同一个实例
+func OpenAssetStore(dir string) (*AssetStore, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	assetStores.Lock()
+	defer assetStores.Unlock()
+	if store, ok := assetStores.stores[dir]; ok {
+		return store, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	manifest := &AssetManifest{Tokens: map[string]string{}, Documents: map[string][]string{}}
+	data, err := os.ReadFile(filepath.Join(dir, AssetManifestFile))
+	if err == nil {
+		if err := json.Unmarshal(data, manifest); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if manifest.Tokens == nil {
+		manifest.Tokens = map[string]string{}
+	}
+	if manifest.Documents == nil {
+		manifest.Documents = map[string][]string{}
+	}
+
+	store := &AssetStore{Dir: dir, manifest: manifest, pending: map[string]bool{}}
+	assetStores.stores[dir] = store
+	return store, nil
+}
+
+// Lookup 返回图片 token 已保存的文件名，文件不存在时返回 false
+func (s *AssetStore) Lookup(token string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name, ok := s.manifest.Tokens[token]
+	if !ok {
+		return "", false
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir, name)); err != nil {
+		delete(s.manifest.Tokens, token)
+		return "", false
+	}
+	return name, true
+}
+
+// Put 保存图片内容，ext 为包含点的扩展名，返回图片库中的文件名
+func (s *AssetStore) Put(token, ext string, data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:16]) + ext
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := filepath.Join(s.Dir, name)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return "", err
+		}
+	}
+	s.manifest.Tokens[token] = name
+	s.pending[name] = true
+	return name, nil
+}
+
+// RelativePath 返回从 fromDir 指向图片库中文件的相对链接
+func (s *AssetStore) RelativePath(fromDir, name string) (string, error) {
+	fromDir, err := filepath.Abs(fromDir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(fromDir, filepath.Join(s.Dir, name))
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// UpdateDocument 记录文档当前引用的文件，替换上一次导出时的记录，并保存清单
+func (s *AssetStore) UpdateDocument(docToken string, names []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := map[string]bool{}
+	refs := []string{}
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			refs = append(refs, name)
+		}
+		delete(s.pending, name)
+	}
+	sort.Strings(refs)
+	s.manifest.Documents[docToken] = refs
+	return s.save()
+}
+
+// GC 删除没有任何文档引用的文件，返回删除的文件名
+func (s *AssetStore) GC() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	referenced := map[string]bool{}
+	for _, names := range s.manifest.Documents {
+		for _, name := range names {
+			referenced[name] = true
+		}
+	}
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || name == AssetManifestFile || referenced[name] || s.pending[name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.Dir, name)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, name)
+	}
+	for token, name := range s.manifest.Tokens {
+		if !referenced[name] && !s.pending[name] {
+			delete(s.manifest.Tokens, token)
+		}
+	}
+	return removed, s.save()
+}
+
+// 先写入临时文件再重命名，避免中断时留下不完整的清单，调用时需要持有锁
+func (s *AssetStore) save() error {
+	data, err := json.MarshalIndent(s.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(s.Dir, AssetManifestFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.Dir, AssetManifestFile))
+}
@@ -0,0 +1,61 @@
+package core_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssetStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := core.OpenAssetStore(filepath.Join(dir, "assets"))
+	assert.NoError(t, err)
+
+	// 内容相同的图片只保存一份
+	logo, err := store.Put("logo-1", ".png", []byte("logo"))
+	assert.NoError(t, err)
+	logo2, err := store.Put("logo-2", ".png", []byte("logo"))
+	assert.NoError(t, err)
+	assert.Equal(t, logo, logo2)
+	shot, err := store.Put("shot", ".jpg", []byte("screenshot"))
+	assert.NoError(t, err)
+
+	name, ok := store.Lookup("logo-2")
+	assert.True(t, ok)
+	assert.Equal(t, logo, name)
+	_, ok = store.Lookup("unknown")
+	assert.False(t, ok)
+
+	link, err := store.RelativePath(filepath.Join(dir, "docs", "guide"), logo)
+	assert.NoError(t, err)
+	assert.Equal(t, "../../assets/"+logo, link)
+
+	assert.NoError(t, store.UpdateDocument("doc-a", []string{logo, shot}))
+	assert.NoError(t, store.UpdateDocument("doc-b", []string{logo}))
+
+	// 重新导出 doc-a 后截图不再被引用
+	assert.NoError(t, store.UpdateDocument("doc-a", []string{logo}))
+	// 新保存但还没有文档引用的图片不会被清理
+	pending, err := store.Put("new", ".png", []byte("new"))
+	assert.NoError(t, err)
+
+	removed, err := store.GC()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{shot}, removed)
+	assert.NoFileExists(t, filepath.Join(store.Dir, shot))
+	assert.FileExists(t, filepath.Join(store.Dir, logo))
+	assert.FileExists(t, filepath.Join(store.Dir, pending))
+	_, ok = store.Lookup("shot")
+	assert.False(t, ok)
+
+	data, err := os.ReadFile(filepath.Join(store.Dir, core.AssetManifestFile))
+	assert.NoError(t, err)
+	var manifest core.AssetManifest
+	assert.NoError(t, json.Unmarshal(data, &manifest))
+	assert.Equal(t, map[string][]string{"doc-a": {logo}, "doc-b": {logo}}, manifest.Documents)
+	assert.Equal(t, logo, manifest.Tokens["logo-1"])
+}
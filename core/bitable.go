@@ -0,0 +1,348 @@
+package core
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chyroc/lark"
+)
+
+// 多维表格字段类型
+const (
+	BitableFieldTypeText             int64 = 1
+	BitableFieldTypeNumber           int64 = 2
+	BitableFieldTypeSingleSelect     int64 = 3
+	BitableFieldTypeMultiSelect      int64 = 4
+	BitableFieldTypeDate             int64 = 5
+	BitableFieldTypeCheckbox         int64 = 7
+	BitableFieldTypeUser             int64 = 11
+	BitableFieldTypePhone            int64 = 13
+	BitableFieldTypeURL              int64 = 15
+	BitableFieldTypeAttachment       int64 = 17
+	BitableFieldTypeLink             int64 = 18
+	BitableFieldTypeFormula          int64 = 20
+	BitableFieldTypeDuplexLink       int64 = 21
+	BitableFieldTypeLocation         int64 = 22
+	BitableFieldTypeCreatedTime      int64 = 1001
+	BitableFieldTypeModifiedTime     int64 = 1002
+	BitableFieldTypeCreatedUser      int64 = 1003
+	BitableFieldTypeModifiedUser     int64 = 1004
+	BitableFieldTypeAutoSerialNumber int64 = 1005
+)
+
+var BitableFieldTypeName = map[int64]string{
+	BitableFieldTypeText:             "text",
+	BitableFieldTypeNumber:           "number",
+	BitableFieldTypeSingleSelect:     "single_select",
+	BitableFieldTypeMultiSelect:      "multi_select",
+	BitableFieldTypeDate:             "date",
+	BitableFieldTypeCheckbox:         "checkbox",
+	BitableFieldTypeUser:             "user",
+	BitableFieldTypePhone:            "phone",
+	BitableFieldTypeURL:              "url",
+	BitableFieldTypeAttachment:       "attachment",
+	BitableFieldTypeLink:             "link",
+	BitableFieldTypeFormula:          "formula",
+	BitableFieldTypeDuplexLink:       "duplex_link",
+	BitableFieldTypeLocation:         "location",
+	BitableFieldTypeCreatedTime:      "created_time",
+	BitableFieldTypeModifiedTime:     "modified_time",
+	BitableFieldTypeCreatedUser:      "created_user",
+	BitableFieldTypeModifiedUser:     "modified_user",
+	BitableFieldTypeAutoSerialNumber: "auto_serial_number",
+}
+
+// Bitable 多维表格及其全部数据表
+type Bitable struct {
+	AppToken string
+	Name     string
+	Revision int64
+	Tables   []*BitableTable
+}
+
+// BitableTable 多维表格中的一张数据表
+type BitableTable struct {
+	TableID  string
+	Name     string
+	Revision int64
+	Fields   []*lark.GetBitableFieldListRespItem
+	Records  []*lark.GetBitableRecordListRespItem
+}
+
+// BitableAttachment 附件字段中的一个文件
+type BitableAttachment struct {
+	FileToken string `json:"file_token"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Size      int64  `json:"size"`
+}
+
+// GetBitable 获取多维表格中所有数据表的字段和记录
+func (c *Client) GetBitable(ctx context.Context, appToken string) (*Bitable, error) {
+	meta, err := c.GetBitableMeta(ctx, appToken)
+	if err != nil {
+		return nil, err
+	}
+	bitable := &Bitable{
+		AppToken: appToken,
+		Name:     meta.Name,
+		Revision: meta.Revision,
+	}
+
+	tables, err := c.GetBitableTableList(ctx, appToken)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range tables {
+		fields, err := c.GetBitableFieldList(ctx, appToken, item.TableID)
+		if err != nil {
+			return nil, fmt.Errorf("获取数据表 %s 的字段失败: %w", item.Name, err)
+		}
+		records, err := c.GetBitableRecordList(ctx, appToken, item.TableID)
+		if err != nil {
+			return nil, fmt.Errorf("获取数据表 %s 的记录失败: %w", item.Name, err)
+		}
+		bitable.Tables = append(bitable.Tables, &BitableTable{
+			TableID:  item.TableID,
+			Name:     item.Name,
+			Revision: item.Revision,
+			Fields:   fields,
+			Records:  records,
+		})
+	}
+	return bitable, nil
+}
+
+// Attachments 返回数据表中所有附件，按出现顺序去重
+func (t *BitableTable) Attachments() []BitableAttachment {
+	var attachments []BitableAttachment
+	seen := map[string]bool{}
+	for _, record := range t.Records {
+		for _, field := range t.Fields {
+			if field.Type != BitableFieldTypeAttachment {
+				continue
+			}
+			for _, attachment := range parseBitableAttachments(record.Fields[field.FieldName]) {
+				if seen[attachment.FileToken] {
+					continue
+				}
+				seen[attachment.FileToken] = true
+				attachments = append(attachments, attachment)
+			}
+		}
+	}
+	return attachments
+}
+
+func parseBitableAttachments(value interface{}) []BitableAttachment {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	var attachments []BitableAttachment
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		attachment := BitableAttachment{}
+		attachment.FileToken, _ = obj["file_token"].(string)
+		attachment.Name, _ = obj["name"].(string)
+		attachment.Type, _ = obj["type"].(string)
+		if size, ok := obj["size"].(float64); ok {
+			attachment.Size = int64(size)
+		}
+		if attachment.FileToken != "" {
+			attachments = append(attachments, attachment)
+		}
+	}
+	return attachments
+}
+
+// ToCSV 导出为 CSV，第一列为记录 ID，其余列按字段顺序排列
+// assetPaths 为附件 token 到本地路径的映射，附件单元格优先写入本地路径
+func (t *BitableTable) ToCSV(assetPaths map[string]string) (string, error) {
+	buf := new(strings.Builder)
+	writer := csv.NewWriter(buf)
+
+	header := []string{"record_id"}
+	for _, field := range t.Fields {
+		header = append(header, field.FieldName)
+	}
+	if err := writer.Write(header); err != nil {
+		return "", err
+	}
+
+	for _, record := range t.Records {
+		row := []string{record.RecordID}
+		for _, field := range t.Fields {
+			value := record.Fields[field.FieldName]
+			if field.Type == BitableFieldTypeAttachment {
+				var names []string
+				for _, attachment := range parseBitableAttachments(value) {
+					if path, ok := assetPaths[attachment.FileToken]; ok {
+						names = append(names, path)
+					} else {
+						names = append(names, attachment.Name)
+					}
+				}
+				row = append(row, strings.Join(names, ","))
+				continue
+			}
+			row = append(row, bitableValueText(value, field.Type))
+		}
+		if err := writer.Write(row); err != nil {
+			return "", err
+		}
+	}
+
+	writer.Flush()
+	return buf.String(), writer.Error()
+}
+
+// ToNDJSON 导出为每行一条记录的 JSON，字段按名称排序以便对比不同时间的导出结果
+func (t *BitableTable) ToNDJSON() (string, error) {
+	attachmentFields := map[string]bool{}
+	for _, field := range t.Fields {
+		if field.Type == BitableFieldTypeAttachment {
+			attachmentFields[field.FieldName] = true
+		}
+	}
+
+	buf := new(strings.Builder)
+	for _, record := range t.Records {
+		fields := make(map[string]interface{}, len(record.Fields))
+		for name, value := range record.Fields {
+			if attachmentFields[name] {
+				// 临时下载链接每次请求都会变化，不写入导出结果
+				value = parseBitableAttachments(value)
+			}
+			fields[name] = value
+		}
+		line, err := json.Marshal(struct {
+			RecordID string                 `json:"record_id"`
+			Fields   map[string]interface{} `json:"fields"`
+		}{
+			RecordID: record.RecordID,
+			Fields:   fields,
+		})
+		if err != nil {
+			return "", err
+		}
+		buf.Write(line)
+		buf.WriteString("\n")
+	}
+	return buf.String(), nil
+}
+
+// BitableSchema 描述多维表格中各数据表的字段类型
+type BitableSchema struct {
+	AppToken string               `json:"app_token"`
+	Name     string               `json:"name"`
+	Tables   []BitableTableSchema `json:"tables"`
+}
+
+type BitableTableSchema struct {
+	TableID string               `json:"table_id"`
+	Name    string               `json:"name"`
+	Fields  []BitableFieldSchema `json:"fields"`
+}
+
+type BitableFieldSchema struct {
+	FieldID     string                                    `json:"field_id"`
+	FieldName   string                                    `json:"field_name"`
+	Type        int64                                     `json:"type"`
+	TypeName    string                                    `json:"type_name"`
+	Description string                                    `json:"description,omitempty"`
+	Property    *lark.GetBitableFieldListRespItemProperty `json:"property,omitempty"`
+}
+
+// Schema 返回多维表格的字段结构描述
+func (b *Bitable) Schema() *BitableSchema {
+	schema := &BitableSchema{
+		AppToken: b.AppToken,
+		Name:     b.Name,
+		Tables:   []BitableTableSchema{},
+	}
+	for _, table := range b.Tables {
+		tableSchema := BitableTableSchema{
+			TableID: table.TableID,
+			Name:    table.Name,
+			Fields:  []BitableFieldSchema{},
+		}
+		for _, field := range table.Fields {
+			fieldSchema := BitableFieldSchema{
+				FieldID:   field.FieldID,
+				FieldName: field.FieldName,
+				Type:      field.Type,
+				TypeName:  BitableFieldTypeName[field.Type],
+				Property:  field.Property,
+			}
+			if fieldSchema.TypeName == "" {
+				fieldSchema.TypeName = "unknown"
+			}
+			if field.Description != nil {
+				fieldSchema.Description = field.Description.Text
+			}
+			tableSchema.Fields = append(tableSchema.Fields, fieldSchema)
+		}
+		schema.Tables = append(schema.Tables, tableSchema)
+	}
+	return schema
+}
+
+// 将字段值转换为 CSV 中的文本
+func bitableValueText(value interface{}, fieldType int64) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v))
+	case float64:
+		switch fieldType {
+		case BitableFieldTypeDate, BitableFieldTypeCreatedTime, BitableFieldTypeModifiedTime:
+			// 日期字段为毫秒时间戳
+			return time.UnixMilli(int64(v)).UTC().Format(time.RFC3339)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		// 富文本由多个片段组成，直接拼接；人员、多选和关联等由多个值组成，以逗号分隔
+		isRichText := fieldType == BitableFieldTypeText
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, bitableValueText(item, fieldType))
+		}
+		if isRichText {
+			return strings.Join(parts, "")
+		}
+		return strings.Join(parts, ",")
+	case map[string]interface{}:
+		keys := []string{"text", "name", "full_address", "link", "value"}
+		if fieldType == BitableFieldTypeURL {
+			keys = []string{"link", "text", "value"}
+		}
+		for _, key := range keys {
+			if inner, ok := v[key]; ok && inner != nil {
+				if key == "value" {
+					return bitableValueText(inner, fieldType)
+				}
+				if text, ok := inner.(string); ok && text != "" {
+					return text
+				}
+			}
+		}
+		if ids, ok := v["link_record_ids"]; ok {
+			return bitableValueText(ids, fieldType)
+		}
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,76 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/chyroc/lark"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBitableTable() *core.BitableTable {
+	return &core.BitableTable{
+		TableID: "tblsRc9GRRXKqhvW",
+		Name:    "Tasks",
+		Fields: []*lark.GetBitableFieldListRespItem{
+			{FieldID: "fld1", FieldName: "Title", Type: core.BitableFieldTypeText},
+			{FieldID: "fld2", FieldName: "Due", Type: core.BitableFieldTypeDate},
+			{FieldID: "fld3", FieldName: "Tags", Type: core.BitableFieldTypeMultiSelect},
+			{FieldID: "fld4", FieldName: "Files", Type: core.BitableFieldTypeAttachment},
+		},
+		Records: []*lark.GetBitableRecordListRespItem{
+			{
+				RecordID: "rec1",
+				Fields: map[string]interface{}{
+					"Title": []interface{}{
+						map[string]interface{}{"type": "text", "text": "Write "},
+						map[string]interface{}{"type": "url", "text": "docs", "link": "https://example.com"},
+					},
+					"Due":  float64(1672531200000),
+					"Tags": []interface{}{"a", "b"},
+					"Files": []interface{}{
+						map[string]interface{}{"file_token": "boxA", "name": "a.png", "type": "image/png", "size": float64(10), "tmp_url": "https://tmp"},
+					},
+				},
+			},
+			{
+				RecordID: "rec2",
+				Fields: map[string]interface{}{
+					"Title": "plain",
+					"Files": []interface{}{
+						map[string]interface{}{"file_token": "boxA", "name": "a.png"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestBitableTableToCSV(t *testing.T) {
+	table := newTestBitableTable()
+	assert.Equal(t, 1, len(table.Attachments()))
+
+	content, err := table.ToCSV(map[string]string{"boxA": "assets/boxA.png"})
+	assert.NoError(t, err)
+	assert.Equal(t, "record_id,Title,Due,Tags,Files\n"+
+		"rec1,Write docs,2023-01-01T00:00:00Z,\"a,b\",assets/boxA.png\n"+
+		"rec2,plain,,,assets/boxA.png\n", content)
+}
+
+func TestBitableTableToNDJSON(t *testing.T) {
+	content, err := newTestBitableTable().ToNDJSON()
+	assert.NoError(t, err)
+	assert.NotContains(t, content, "tmp_url")
+	assert.Contains(t, content, `{"record_id":"rec2","fields":{"Files":[{"file_token":"boxA","name":"a.png","type":"","size":0}],"Title":"plain"}}`+"\n")
+}
+
+func TestBitableSchema(t *testing.T) {
+	bitable := &core.Bitable{
+		AppToken: "bascn",
+		Name:     "Project",
+		Tables:   []*core.BitableTable{newTestBitableTable()},
+	}
+	schema := bitable.Schema()
+	assert.Equal(t, 1, len(schema.Tables))
+	assert.Equal(t, "attachment", schema.Tables[0].Fields[3].TypeName)
+}
@@ -4,15 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/chyroc/lark"
-	"github.com/chyroc/lark_rate_limiter"
 )
 
 type Client struct {
@@ -20,21 +22,87 @@ type Client struct {
 	httpClient *http.Client // 添加 HTTP 客户端
 	appID      string       // 添加应用 ID
 	appSecret  string       // 添加应用密钥
+	baseURL    string       // 开放平台接口地址
+	domain     string       // 文档链接使用的域名
+
+	tokenMu       sync.Mutex // 保护租户访问令牌缓存
+	tenantToken   string
+	tokenExpireAt time.Time
+	sdkStore      lark.Store // SDK 缓存租户令牌的存储，凭证失效时需要一起清除
+
+	userMu         sync.Mutex // 保护用户令牌
+	userTokenStore UserTokenStore
+	userToken      *UserToken
+
+	retryPolicy RetryPolicy
+	rateLimiter *RateLimiter
+}
+
+// 访问令牌在过期前提前刷新的时间
+const tokenRefreshMargin = 5 * time.Minute
+
+// ClientOption 创建客户端时的可选配置
+type ClientOption func(*Client)
+
+// WithBaseURL 设置开放平台接口地址，例如国际版的 https://open.larksuite.com
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) {
+		if baseURL != "" {
+			c.baseURL = strings.TrimSuffix(baseURL, "/")
+		}
+	}
 }
 
-func NewClient(appID, appSecret string) *Client {
-	return &Client{
-		larkClient: lark.New(
-			lark.WithAppCredential(appID, appSecret),
-			lark.WithTimeout(60*time.Second),
-			lark.WithApiMiddleware(lark_rate_limiter.Wait(4, 4)),
-		),
+// WithDomain 设置生成文档链接时使用的域名，例如 larksuite.com
+func WithDomain(domain string) ClientOption {
+	return func(c *Client) {
+		if domain != "" {
+			c.domain = domain
+		}
+	}
+}
+
+func NewClient(appID, appSecret string, options ...ClientOption) *Client {
+	c := &Client{
 		httpClient: &http.Client{
 			Timeout: 60 * time.Second,
 		},
-		appID:     appID,
-		appSecret: appSecret,
-	}
+		appID:       appID,
+		appSecret:   appSecret,
+		baseURL:     DefaultBaseURL,
+		domain:      DefaultDomain,
+		retryPolicy: DefaultRetryPolicy,
+		sdkStore:    lark.NewStoreMemory(),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	if c.rateLimiter == nil {
+		c.rateLimiter = AppRateLimiter(appID)
+	}
+	c.larkClient = lark.New(
+		lark.WithAppCredential(appID, appSecret),
+		lark.WithOpenBaseURL(c.baseURL),
+		lark.WithTimeout(60*time.Second),
+		lark.WithStore(c.sdkStore),
+		lark.WithApiMiddleware(c.retryMiddleware, c.errorMiddleware, c.rateLimitMiddleware, c.userTokenMiddleware),
+	)
+	return c
+}
+
+// BaseURL 返回开放平台接口地址
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Domain 返回文档链接使用的域名
+func (c *Client) Domain() string {
+	return c.domain
+}
+
+// DocumentURL 生成文档的访问链接，docType 为链接中的路径，如 docx、wiki、sheets
+func (c *Client) DocumentURL(docType, token string) string {
+	return fmt.Sprintf("https://%s/%s/%s", c.domain, docType, token)
 }
 
 func (c *Client) DownloadImage(ctx context.Context, imgToken, outDir string) (string, error) {
@@ -77,34 +145,51 @@ func (c *Client) DownloadImageRaw(ctx context.Context, imgToken, imgDir string)
 }
 
 func (c *Client) GetDocxContent(ctx context.Context, docToken string) (*lark.DocxDocument, []*lark.DocxBlock, error) {
+	docx, err := c.GetDocxDocument(ctx, docToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	blocks, err := c.GetDocxBlocks(ctx, docx.DocumentID)
+	if err != nil {
+		return docx, nil, err
+	}
+	return docx, blocks, nil
+}
+
+// GetDocxDocument 只获取文档的标题和版本号，用于判断文档是否有更新
+func (c *Client) GetDocxDocument(ctx context.Context, docToken string) (*lark.DocxDocument, error) {
 	resp, _, err := c.larkClient.Drive.GetDocxDocument(ctx, &lark.GetDocxDocumentReq{
 		DocumentID: docToken,
 	})
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	docx := &lark.DocxDocument{
+	return &lark.DocxDocument{
 		DocumentID: resp.Document.DocumentID,
 		RevisionID: resp.Document.RevisionID,
 		Title:      resp.Document.Title,
-	}
+	}, nil
+}
+
+// GetDocxBlocks 分页获取文档的所有块
+func (c *Client) GetDocxBlocks(ctx context.Context, documentID string) ([]*lark.DocxBlock, error) {
 	var blocks []*lark.DocxBlock
 	var pageToken *string
 	for {
-		resp2, _, err := c.larkClient.Drive.GetDocxBlockListOfDocument(ctx, &lark.GetDocxBlockListOfDocumentReq{
-			DocumentID: docx.DocumentID,
+		resp, _, err := c.larkClient.Drive.GetDocxBlockListOfDocument(ctx, &lark.GetDocxBlockListOfDocumentReq{
+			DocumentID: documentID,
 			PageToken:  pageToken,
 		})
 		if err != nil {
-			return docx, nil, err
+			return nil, err
 		}
-		blocks = append(blocks, resp2.Items...)
-		pageToken = &resp2.PageToken
-		if !resp2.HasMore {
+		blocks = append(blocks, resp.Items...)
+		pageToken = &resp.PageToken
+		if !resp.HasMore {
 			break
 		}
 	}
-	return docx, blocks, nil
+	return blocks, nil
 }
 
 func (c *Client) GetWikiNodeInfo(ctx context.Context, token string) (*lark.GetWikiNodeRespNode, error) {
@@ -168,7 +253,7 @@ func (c *Client) GetWikiNodeList(ctx context.Context, spaceID string, parentNode
 	nodes := resp.Items
 
 	for resp.HasMore {
-		resp, _, err := c.larkClient.Drive.GetWikiNodeList(ctx, &lark.GetWikiNodeListReq{
+		resp, _, err = c.larkClient.Drive.GetWikiNodeList(ctx, &lark.GetWikiNodeListReq{
 			SpaceID:         spaceID,
 			PageSize:        nil,
 			PageToken:       &resp.PageToken,
@@ -187,11 +272,32 @@ func (c *Client) GetWikiNodeList(ctx context.Context, spaceID string, parentNode
 
 // WikiNode 定义知识库节点结构
 type WikiNode struct {
-	NodeToken string `json:"node_token"`
-	ObjToken  string `json:"obj_token"`
-	ObjType   string `json:"obj_type"`
-	Title     string `json:"title"`
-	SpaceID   string // 添加SpaceID字段
+	NodeToken       string `json:"node_token"`
+	ObjToken        string `json:"obj_token"`
+	ObjType         string `json:"obj_type"`
+	Title           string `json:"title"`
+	SpaceID         string // 添加SpaceID字段
+	ParentNodeToken string `json:"parent_node_token"` // 顶级节点为空
+	NodeType        string `json:"node_type"`         // origin 为实体，shortcut 为快捷方式
+	OriginNodeToken string `json:"origin_node_token"` // 快捷方式对应的实体节点
+	HasChild        bool   `json:"has_child"`
+}
+
+// WikiNodeTypeShortcut 快捷方式节点，子节点属于对应的实体节点
+const WikiNodeTypeShortcut = "shortcut"
+
+func newWikiNode(item *lark.GetWikiNodeListRespItem, spaceID string) *WikiNode {
+	return &WikiNode{
+		NodeToken:       item.NodeToken,
+		ObjToken:        item.ObjToken,
+		ObjType:         item.ObjType,
+		Title:           item.Title,
+		SpaceID:         spaceID,
+		ParentNodeToken: item.ParentNodeToken,
+		NodeType:        item.NodeType,
+		OriginNodeToken: item.OriginNodeToken,
+		HasChild:        item.HasChild,
+	}
 }
 
 // GetWikiNodeChildren 获取知识库节点的子节点
@@ -203,52 +309,39 @@ func (c *Client) GetWikiNodeChildren(ctx context.Context, nodeToken string) ([]*
 	if err != nil {
 		return nil, fmt.Errorf("获取节点信息失败: %w", err)
 	}
+	return c.ListWikiNodes(ctx, nodeInfo.Node.SpaceID, nodeToken)
+}
 
-	// 使用正确的API调用获取子节点，提供 SpaceID
-	resp, _, err := c.larkClient.Drive.GetWikiNodeList(ctx, &lark.GetWikiNodeListReq{
-		SpaceID:         nodeInfo.Node.SpaceID,
-		ParentNodeToken: &nodeToken,
-	})
-	if err != nil {
-		return nil, err
+// ListWikiNodes 获取知识空间中 parentNodeToken 的全部子节点，parentNodeToken 为空时获取顶级节点
+// 任意一页获取失败都返回错误，不会返回不完整的列表
+func (c *Client) ListWikiNodes(ctx context.Context, spaceID, parentNodeToken string) ([]*WikiNode, error) {
+	var parent *string
+	if parentNodeToken != "" {
+		parent = &parentNodeToken
 	}
 
 	var nodes []*WikiNode
-	for _, item := range resp.Items {
-		node := &WikiNode{
-			NodeToken: item.NodeToken,
-			ObjToken:  item.ObjToken,
-			ObjType:   item.ObjType,
-			Title:     item.Title,
-			SpaceID:   nodeInfo.Node.SpaceID, // 保存SpaceID
-		}
-		nodes = append(nodes, node)
-	}
-
-	// 如果有更多页，继续获取
-	for resp.HasMore {
-		resp, _, err = c.larkClient.Drive.GetWikiNodeList(ctx, &lark.GetWikiNodeListReq{
-			SpaceID:         nodeInfo.Node.SpaceID,
-			PageToken:       &resp.PageToken,
-			ParentNodeToken: &nodeToken,
+	var pageToken *string
+	for {
+		resp, _, err := c.larkClient.Drive.GetWikiNodeList(ctx, &lark.GetWikiNodeListReq{
+			SpaceID:         spaceID,
+			PageToken:       pageToken,
+			ParentNodeToken: parent,
 		})
 		if err != nil {
-			return nodes, nil // 返回已获取的节点
+			if len(nodes) > 0 {
+				return nil, fmt.Errorf("获取子节点列表失败，已获取 %d 个节点: %w", len(nodes), err)
+			}
+			return nil, err
 		}
-
 		for _, item := range resp.Items {
-			node := &WikiNode{
-				NodeToken: item.NodeToken,
-				ObjToken:  item.ObjToken,
-				ObjType:   item.ObjType,
-				Title:     item.Title,
-				SpaceID:   nodeInfo.Node.SpaceID, // 保存SpaceID
-			}
-			nodes = append(nodes, node)
+			nodes = append(nodes, newWikiNode(item, spaceID))
+		}
+		if !resp.HasMore {
+			return nodes, nil
 		}
+		pageToken = &resp.PageToken
 	}
-
-	return nodes, nil
 }
 
 // WikiSpace 表示知识库空间信息
@@ -257,8 +350,15 @@ type WikiSpace struct {
 	Name    string `json:"name"`
 }
 
-// GetTenantAccessToken 获取租户访问令牌
+// GetTenantAccessToken 获取租户访问令牌，令牌缓存到过期前一段时间再刷新，可以并发调用
 func (c *Client) GetTenantAccessToken(ctx context.Context) (string, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+
+	if c.tenantToken != "" && time.Now().Before(c.tokenExpireAt) {
+		return c.tenantToken, nil
+	}
+
 	// 构建请求体
 	reqBody := map[string]string{
 		"app_id":     c.appID,
@@ -269,111 +369,130 @@ func (c *Client) GetTenantAccessToken(ctx context.Context) (string, error) {
 		return "", err
 	}
 
-	// 发送请求
-	req, err := http.NewRequestWithContext(
-		ctx,
-		"POST",
-		"https://open.feishu.cn/open-apis/auth/v3/tenant_access_token/internal",
-		bytes.NewBuffer(jsonData),
-	)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	// 解析响应
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
 	var result struct {
-		Code              int    `json:"code"`
-		Msg               string `json:"msg"`
 		TenantAccessToken string `json:"tenant_access_token"`
 		Expire            int    `json:"expire"`
 	}
-
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", err
-	}
-
-	if result.Code != 0 {
-		return "", fmt.Errorf("获取访问令牌失败: %s (代码: %d)", result.Msg, result.Code)
+	err = c.doRawRequest(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(
+			ctx,
+			"POST",
+			c.baseURL+"/open-apis/auth/v3/tenant_access_token/internal",
+			bytes.NewBuffer(jsonData),
+		)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		return req, nil
+	}, &result)
+	if err != nil {
+		return "", fmt.Errorf("获取访问令牌失败: %w", err)
 	}
 
+	c.tenantToken = result.TenantAccessToken
+	c.tokenExpireAt = time.Now().Add(time.Duration(result.Expire)*time.Second - tokenRefreshMargin)
 	return result.TenantAccessToken, nil
 }
 
-// GetAllWikiSpaces 获取用户可访问的所有知识库空间
-func (c *Client) GetAllWikiSpaces(ctx context.Context) ([]*WikiSpace, error) {
-	// 获取访问令牌
-	token, err := c.GetTenantAccessToken(ctx)
-	if err != nil {
-		fmt.Printf("获取访问令牌失败: %v\n", err)
-		return nil, err
-	}
-	fmt.Printf("成功获取访问令牌: %s...\n", token[:10])
+// 接口返回凭证无效时清除缓存的租户令牌，下次请求重新获取，避免令牌被吊销或应用密钥轮换后一直使用失效的令牌
+func (c *Client) invalidateTenantToken(err error) {
+	if !errors.Is(err, ErrInvalidCredentials) {
+		return
+	}
+	c.tokenMu.Lock()
+	c.tenantToken = ""
+	c.tokenExpireAt = time.Time{}
+	c.tokenMu.Unlock()
+	// SDK 的内存存储在读取时删除有效期不足的值，写入过期的空值即可清除
+	c.sdkStore.Set(context.Background(), "internal-tenant-token:"+c.appID, "", 0)
+}
 
-	// 构建请求URL
-	url := "https://open.feishu.cn/open-apis/wiki/v2/spaces"
-	fmt.Printf("请求URL: %s\n", url)
+// 直接发送 HTTP 请求并将响应解析到 result，按照重试策略重试
+// newRequest 在每次尝试时调用，请求体只能读取一次，需要重新创建请求；其中不能再发送需要重试的请求
+func (c *Client) doRawRequest(ctx context.Context, newRequest func() (*http.Request, error), result interface{}) error {
+	return c.retry(ctx, true, func() error {
+		req, err := newRequest()
+		if err != nil {
+			return err
+		}
+		if err := c.rateLimiter.Wait(ctx, req.URL.Path); err != nil {
+			return wrapAPIError(err, nil)
+		}
+		err = c.sendRawRequest(req, result)
+		c.rateLimiter.Observe(req.URL.Path, err)
+		// 获取令牌的请求没有 Authorization，此时已持有 tokenMu，不能清除缓存
+		if req.Header.Get("Authorization") != "" {
+			c.invalidateTenantToken(err)
+		}
+		return err
+	})
+}
 
-	// 发送请求
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// 发送请求并检查飞书返回的错误码
+func (c *Client) sendRawRequest(req *http.Request, result interface{}) error {
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		fmt.Printf("创建请求失败: %v\n", err)
-		return nil, err
+		return wrapAPIError(err, nil)
 	}
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
-	fmt.Printf("请求头: Authorization=Bearer %s..., Content-Type=%s\n",
-		token[:10], "application/json; charset=utf-8")
+	defer resp.Body.Close()
 
-	resp, err := c.httpClient.Do(req)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Printf("发送请求失败: %v\n", err)
-		return nil, err
+		return wrapAPIError(err, nil)
 	}
-	defer resp.Body.Close()
 
-	fmt.Printf("响应状态码: %d\n", resp.StatusCode)
+	var status struct {
+		Code int64  `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return newAPIError(0, resp.Status, resp)
+		}
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	if status.Code != 0 {
+		return newAPIError(status.Code, status.Msg, resp)
+	}
+	return json.Unmarshal(body, result)
+}
 
-	// 解析响应
-	body, err := io.ReadAll(resp.Body)
+// 发送携带访问令牌的 GET 请求，已登录时使用用户令牌
+// 令牌在重试之前获取，获取令牌的请求已经按照重试策略重试过，不再与这次请求的重试叠加
+func (c *Client) doAuthorizedRequest(ctx context.Context, url string, result interface{}) error {
+	token, err := c.accessToken(ctx)
 	if err != nil {
-		fmt.Printf("读取响应体失败: %v\n", err)
-		return nil, err
+		return err
 	}
+	return c.doRawRequest(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		return req, nil
+	}, result)
+}
 
-	// 打印完整的响应体
-	fmt.Printf("响应体: %s\n", string(body))
+// GetAllWikiSpaces 获取用户可访问的所有知识库空间
+func (c *Client) GetAllWikiSpaces(ctx context.Context) ([]*WikiSpace, error) {
+	url := c.baseURL + "/open-apis/wiki/v2/spaces"
+	fmt.Printf("请求URL: %s\n", url)
 
 	var result struct {
-		Code int `json:"code"`
 		Data struct {
 			Items []struct {
 				SpaceID string `json:"space_id"`
 				Name    string `json:"name"`
 			} `json:"items"`
 		} `json:"data"`
-		Msg string `json:"msg"`
-	}
-
-	if err := json.Unmarshal(body, &result); err != nil {
-		fmt.Printf("解析JSON失败: %v\n", err)
-		return nil, err
 	}
-
-	if result.Code != 0 {
-		fmt.Printf("API返回错误: 代码=%d, 消息=%s\n", result.Code, result.Msg)
-		return nil, fmt.Errorf("API错误: %s (代码: %d)", result.Msg, result.Code)
+	err := c.doAuthorizedRequest(ctx, url, &result)
+	if err != nil {
+		fmt.Printf("获取知识库空间失败: %v\n", err)
+		return nil, fmt.Errorf("API错误: %w", err)
 	}
 
 	// 构建返回结果
@@ -393,77 +512,47 @@ func (c *Client) GetAllWikiSpaces(ctx context.Context) ([]*WikiSpace, error) {
 // GetWikiNodeListWithPagination 获取知识库节点列表，支持分页
 func (c *Client) GetWikiNodeListWithPagination(ctx context.Context, spaceID string, pageToken *string) ([]*WikiNode, string, error) {
 	// 构建请求URL
-	apiURL := fmt.Sprintf("https://open.feishu.cn/open-apis/wiki/v2/spaces/%s/nodes", spaceID)
+	apiURL := fmt.Sprintf("%s/open-apis/wiki/v2/spaces/%s/nodes", c.baseURL, spaceID)
 
 	// 添加分页参数
 	if pageToken != nil && *pageToken != "" {
 		apiURL = fmt.Sprintf("%s?page_token=%s", apiURL, *pageToken)
 	}
 
-	// 创建请求
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-	if err != nil {
-		return nil, "", err
-	}
-
-	// 添加认证头
-	token, err := c.GetTenantAccessToken(ctx)
-	if err != nil {
-		return nil, "", err
-	}
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
-
-	// 发送请求
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, "", err
-	}
-	defer resp.Body.Close()
-
-	// 读取响应
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, "", err
-	}
-
-	// 打印响应体，帮助调试
-	fmt.Printf("API响应: %s\n", string(body))
-
-	// 解析响应
 	var result struct {
-		Code int    `json:"code"`
-		Msg  string `json:"msg"`
 		Data struct {
 			Items []struct {
-				NodeToken string `json:"node_token"`
-				ObjToken  string `json:"obj_token"`
-				ObjType   string `json:"obj_type"`
-				Title     string `json:"title"`
+				NodeToken       string `json:"node_token"`
+				ObjToken        string `json:"obj_token"`
+				ObjType         string `json:"obj_type"`
+				Title           string `json:"title"`
+				ParentNodeToken string `json:"parent_node_token"`
+				NodeType        string `json:"node_type"`
+				OriginNodeToken string `json:"origin_node_token"`
+				HasChild        bool   `json:"has_child"`
 			} `json:"items"`
 			PageToken string `json:"page_token"`
 			HasMore   bool   `json:"has_more"`
 		} `json:"data"`
 	}
-
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, "", err
-	}
-
-	// 检查错误
-	if result.Code != 0 {
-		return nil, "", fmt.Errorf("API错误: %d - %s", result.Code, result.Msg)
+	err := c.doAuthorizedRequest(ctx, apiURL, &result)
+	if err != nil {
+		return nil, "", fmt.Errorf("API错误: %w", err)
 	}
 
 	// 转换结果
 	nodes := make([]*WikiNode, len(result.Data.Items))
 	for i, item := range result.Data.Items {
 		nodes[i] = &WikiNode{
-			NodeToken: item.NodeToken,
-			ObjToken:  item.ObjToken,
-			ObjType:   item.ObjType,
-			Title:     item.Title,
-			SpaceID:   spaceID,
+			NodeToken:       item.NodeToken,
+			ObjToken:        item.ObjToken,
+			ObjType:         item.ObjType,
+			Title:           item.Title,
+			SpaceID:         spaceID,
+			ParentNodeToken: item.ParentNodeToken,
+			NodeType:        item.NodeType,
+			OriginNodeToken: item.OriginNodeToken,
+			HasChild:        item.HasChild,
 		}
 		// 打印每个节点的信息，帮助调试
 		fmt.Printf("节点 %d: 标题=%s, NodeToken=%s, ObjToken=%s, ObjType=%s\n",
@@ -478,3 +567,133 @@ func (c *Client) GetWikiNodeListWithPagination(ctx context.Context, spaceID stri
 
 	return nodes, nextPageToken, nil
 }
+
+// GetSheetMeta 获取电子表格元信息，包括各工作表及其合并单元格范围
+func (c *Client) GetSheetMeta(ctx context.Context, spreadsheetToken string) (*lark.GetSheetMetaResp, error) {
+	resp, _, err := c.larkClient.Drive.GetSheetMeta(ctx, &lark.GetSheetMetaReq{
+		SpreadSheetToken: spreadsheetToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetSheetValues 读取电子表格指定范围内的单元格值
+// 公式单元格返回计算后的结果，日期按表格中设置的格式返回
+func (c *Client) GetSheetValues(ctx context.Context, spreadsheetToken, valueRange string) ([][]interface{}, error) {
+	// SDK 中的 SheetContent 无法解析小数和负数，这里自行解析原始值
+	var resp struct {
+		Code int64  `json:"code"`
+		Msg  string `json:"msg"`
+		Data struct {
+			ValueRange struct {
+				Range  string          `json:"range"`
+				Values [][]interface{} `json:"values"`
+			} `json:"valueRange"`
+		} `json:"data"`
+	}
+	_, err := c.larkClient.RawRequest(ctx, &lark.RawRequestReq{
+		Scope:  "Drive",
+		API:    "GetSheetValue",
+		Method: "GET",
+		URL:    c.baseURL + "/open-apis/sheets/v2/spreadsheets/:spreadsheetToken/values/:range",
+		Body: &struct {
+			SpreadSheetToken     string `path:"spreadsheetToken" json:"-"`
+			Range                string `path:"range" json:"-"`
+			ValueRenderOption    string `query:"valueRenderOption" json:"-"`
+			DateTimeRenderOption string `query:"dateTimeRenderOption" json:"-"`
+		}{
+			SpreadSheetToken:     spreadsheetToken,
+			Range:                valueRange,
+			ValueRenderOption:    "FormattedValue",
+			DateTimeRenderOption: "FormattedString",
+		},
+		MethodOption:          &lark.MethodOption{},
+		NeedTenantAccessToken: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data.ValueRange.Values, nil
+}
+
+// GetBitableMeta 获取多维表格元数据
+func (c *Client) GetBitableMeta(ctx context.Context, appToken string) (*lark.GetBitableMetaRespApp, error) {
+	resp, _, err := c.larkClient.Bitable.GetBitableMeta(ctx, &lark.GetBitableMetaReq{
+		AppToken: appToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.App, nil
+}
+
+// GetBitableTableList 分页获取多维表格中的全部数据表
+func (c *Client) GetBitableTableList(ctx context.Context, appToken string) ([]*lark.GetBitableTableListRespItem, error) {
+	var tables []*lark.GetBitableTableListRespItem
+	var pageToken *string
+	for {
+		resp, _, err := c.larkClient.Bitable.GetBitableTableList(ctx, &lark.GetBitableTableListReq{
+			AppToken:  appToken,
+			PageToken: pageToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, resp.Items...)
+		if !resp.HasMore {
+			break
+		}
+		pageToken = &resp.PageToken
+	}
+	return tables, nil
+}
+
+// GetBitableFieldList 分页获取数据表的全部字段
+func (c *Client) GetBitableFieldList(ctx context.Context, appToken, tableID string) ([]*lark.GetBitableFieldListRespItem, error) {
+	var fields []*lark.GetBitableFieldListRespItem
+	var pageToken *string
+	pageSize := int64(100)
+	for {
+		resp, _, err := c.larkClient.Bitable.GetBitableFieldList(ctx, &lark.GetBitableFieldListReq{
+			AppToken:  appToken,
+			TableID:   tableID,
+			PageToken: pageToken,
+			PageSize:  &pageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, resp.Items...)
+		if !resp.HasMore {
+			break
+		}
+		pageToken = &resp.PageToken
+	}
+	return fields, nil
+}
+
+// GetBitableRecordList 分页获取数据表的全部记录
+func (c *Client) GetBitableRecordList(ctx context.Context, appToken, tableID string) ([]*lark.GetBitableRecordListRespItem, error) {
+	var records []*lark.GetBitableRecordListRespItem
+	var pageToken *string
+	pageSize := int64(500)
+	for {
+		resp, _, err := c.larkClient.Bitable.GetBitableRecordList(ctx, &lark.GetBitableRecordListReq{
+			AppToken:  appToken,
+			TableID:   tableID,
+			PageToken: pageToken,
+			PageSize:  &pageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, resp.Items...)
+		if !resp.HasMore {
+			break
+		}
+		pageToken = &resp.PageToken
+	}
+	return records, nil
+}
@@ -40,6 +40,18 @@ func TestNewClient(t *testing.T) {
 	}
 }
 
+func TestNewClientWithDomain(t *testing.T) {
+	c := core.NewClient("id", "secret")
+	if c.BaseURL() != core.DefaultBaseURL || c.DocumentURL("docx", "token") != "https://feishu.cn/docx/token" {
+		t.Errorf("Unexpected default domain: %s, %s", c.BaseURL(), c.Domain())
+	}
+
+	c = core.NewClient("id", "secret", core.WithBaseURL(core.LarkBaseURL+"/"), core.WithDomain(core.LarkDomain))
+	if c.BaseURL() != core.LarkBaseURL || c.DocumentURL("wiki", "token") != "https://larksuite.com/wiki/token" {
+		t.Errorf("Unexpected lark domain: %s, %s", c.BaseURL(), c.Domain())
+	}
+}
+
 func TestDownloadImage(t *testing.T) {
 	appID, appSecret := getIdAndSecretFromEnv(t)
 	c := core.NewClient(appID, appSecret)
@@ -3,25 +3,91 @@ package core
 import (
 	"encoding/json"
 	"os"
-	"path"
 	"path/filepath"
 )
 
 type Config struct {
 	Feishu FeishuConfig `json:"feishu"`
 	Output OutputConfig `json:"output"`
+	Server ServerConfig `json:"server"`
+	// Profiles 其他租户的命名配置，可以按名称或文档链接的域名选择
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+	// ProfileName 当前使用的命名配置，使用全局配置时为空
+	ProfileName string `json:"-"`
 }
 
 type FeishuConfig struct {
 	AppId     string `json:"app_id"`
 	AppSecret string `json:"app_secret"`
+	// BaseURL 开放平台接口地址，国际版和私有化部署需要修改
+	BaseURL string `json:"base_url"`
+	// Domain 文档链接的域名
+	Domain string `json:"domain"`
+	// MaxRetries 接口调用失败后最多重试的次数，为 0 时使用默认值，小于 0 时不重试
+	MaxRetries int `json:"max_retries,omitempty"`
 }
 
+// 飞书和 Lark 的默认地址
+const (
+	DefaultBaseURL = "https://open.feishu.cn"
+	DefaultDomain  = "feishu.cn"
+	LarkBaseURL    = "https://open.larksuite.com"
+	LarkDomain     = "larksuite.com"
+)
+
 type OutputConfig struct {
+	// OutputPath 导出文件的保存目录，为空时使用当前目录下的 output
+	OutputPath      string `json:"output_path"`
 	ImageDir        string `json:"image_dir"`
 	TitleAsFilename bool   `json:"title_as_filename"`
 	UseHTMLTags     bool   `json:"use_html_tags"`
 	SkipImgDownload bool   `json:"skip_img_download"`
+	SheetFormat     string `json:"sheet_format"`
+	DocumentJSON    bool   `json:"document_json"`
+	// AssetConcurrency 同时下载的图片和附件数量
+	AssetConcurrency int `json:"asset_concurrency"`
+	// CrawlWorkers 遍历知识库节点树时同时请求的数量
+	CrawlWorkers int `json:"crawl_workers"`
+	// AssetStoreDir 多个文档共享的图片目录，为空时每个文档使用单独的图片目录
+	AssetStoreDir string `json:"asset_store_dir"`
+	// Image 下载图片后的格式转换、缩放和压缩选项
+	Image ImageOptions `json:"image"`
+	// AssetSink 图片的保存位置，可以上传到 S3 兼容的对象存储
+	AssetSink AssetSinkConfig `json:"asset_sink"`
+	// Filename 导出文件的命名规则
+	Filename FilenameOptions `json:"filename"`
+}
+
+// ServerConfig 后端服务的监听地址、访问控制和 TLS 证书
+type ServerConfig struct {
+	// Host 监听的地址，为空时只监听本机的 127.0.0.1
+	Host string `json:"host"`
+	// AllowedOrigins 允许跨域访问的网页来源，例如 http://localhost:3000，* 表示允许所有来源
+	AllowedOrigins []string `json:"allowed_origins"`
+	// DisableAuth 关闭访问令牌校验，只应在本机使用
+	DisableAuth bool `json:"disable_auth"`
+	// TLSCertFile 和 TLSKeyFile 都设置时使用 HTTPS
+	TLSCertFile string `json:"tls_cert_file"`
+	TLSKeyFile  string `json:"tls_key_file"`
+	// OutputRoots 接口允许写入的目录，为空时只允许写入各配置的输出目录和共享图片目录
+	OutputRoots []string `json:"output_roots"`
+	// ImportRoots 导入接口允许读取 Markdown 文件和图片的目录，为空时与允许写入的目录相同
+	ImportRoots []string `json:"import_roots"`
+	// AuditLog 审计日志文件，记录被拒绝的写入请求，为空时使用日志目录下的 audit.log
+	AuditLog string `json:"audit_log"`
+	// PublicURL 浏览器访问服务的地址，用于生成授权回调地址，为空时使用监听的地址
+	PublicURL string `json:"public_url"`
+}
+
+// ClientOptions 返回按照配置创建客户端所需的选项
+func (f FeishuConfig) ClientOptions() []ClientOption {
+	options := []ClientOption{WithBaseURL(f.BaseURL), WithDomain(f.Domain)}
+	if f.MaxRetries != 0 {
+		policy := DefaultRetryPolicy
+		policy.MaxRetries = max(f.MaxRetries, 0)
+		options = append(options, WithRetryPolicy(policy))
+	}
+	return options
 }
 
 func NewConfig(appId, appSecret string) *Config {
@@ -29,12 +95,17 @@ func NewConfig(appId, appSecret string) *Config {
 		Feishu: FeishuConfig{
 			AppId:     appId,
 			AppSecret: appSecret,
+			BaseURL:   DefaultBaseURL,
+			Domain:    DefaultDomain,
 		},
 		Output: OutputConfig{
-			ImageDir:        "static",
-			TitleAsFilename: false,
-			UseHTMLTags:     false,
-			SkipImgDownload: false,
+			ImageDir:         "static",
+			TitleAsFilename:  false,
+			UseHTMLTags:      false,
+			SkipImgDownload:  false,
+			SheetFormat:      SheetFormatMarkdown,
+			AssetConcurrency: DefaultAssetConcurrency,
+			CrawlWorkers:     DefaultCrawlWorkers,
 		},
 	}
 }
@@ -44,7 +115,7 @@ func GetConfigFilePath() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	configFilePath := path.Join(configPath, "feishu2md", "config.json")
+	configFilePath := filepath.Join(configPath, "feishu2md", "config.json")
 	return configFilePath, nil
 }
 
@@ -58,18 +129,46 @@ func ReadConfigFromFile(configPath string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	// 加密的密钥使用环境变量或口令命令提供的口令解密
+	if config.HasEncryptedSecrets() {
+		passphrase, err := LookupPassphrase()
+		if err != nil {
+			return nil, err
+		}
+		if passphrase == "" {
+			return nil, ErrPassphraseRequired
+		}
+		if err := config.DecryptSecrets(passphrase); err != nil {
+			return nil, err
+		}
+	}
 	return config, nil
 }
 
+// WriteConfig2File 保存配置，设置了加密口令时密钥加密后写入，文件只有当前用户可以读写
 func (conf *Config) WriteConfig2File(configPath string) error {
-	err := os.MkdirAll(filepath.Dir(configPath), 0o755)
+	passphrase, err := LookupPassphrase()
+	if err != nil {
+		return err
+	}
+	config := *conf
+	if passphrase != "" {
+		if err := config.EncryptSecrets(passphrase); err != nil {
+			return err
+		}
+	}
+	err = os.MkdirAll(filepath.Dir(configPath), 0o700)
+	if err != nil {
+		return err
+	}
+	file, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return err
 	}
-	file, err := json.MarshalIndent(conf, "", "  ")
+	err = os.WriteFile(configPath, file, 0o600)
 	if err != nil {
 		return err
 	}
-	err = os.WriteFile(configPath, file, 0o644)
-	return err
+	// 已存在的文件不会被 WriteFile 修改权限
+	return os.Chmod(configPath, 0o600)
 }
@@ -0,0 +1,32 @@
+package core_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feishu2md", "config.json")
+	config := core.NewConfig("id", "secret")
+	config.Output.OutputPath = "docs"
+	config.Output.SheetFormat = core.SheetFormatXLSX
+	config.Output.Image.Format = core.ImageFormatWebP
+	assert.NoError(t, config.WriteConfig2File(path))
+
+	loaded, err := core.ReadConfigFromFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, config, loaded)
+}
+
+func TestConfigFileDefaults(t *testing.T) {
+	path := writeTestFile(t, filepath.Join(t.TempDir(), "config.json"), `{"feishu":{"app_id":"id"}}`)
+	config, err := core.ReadConfigFromFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "id", config.Feishu.AppId)
+	assert.Equal(t, core.DefaultDomain, config.Feishu.Domain)
+	assert.Equal(t, core.SheetFormatMarkdown, config.Output.SheetFormat)
+	assert.Empty(t, config.Output.OutputPath)
+}
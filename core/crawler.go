@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultCrawlWorkers 默认同时获取子节点列表的请求数量
+const DefaultCrawlWorkers = 8
+
+// WikiCrawlOptions 遍历知识库节点树的选项
+type WikiCrawlOptions struct {
+	Workers  int // 同时获取子节点列表的请求数量，不大于 0 时使用默认值
+	MaxDepth int // 最大深度，顶级节点的深度为 1，0 表示不限制
+	// OnNode 发现节点时调用，调用是串行的，但不保证节点的顺序
+	// 此时节点的子节点还没有获取，回调中不要读取 Children
+	OnNode func(node *WikiTreeNode)
+}
+
+// WikiTreeNode 知识库节点树中的节点，子节点的顺序与知识库中一致
+type WikiTreeNode struct {
+	*WikiNode
+	Depth    int
+	Children []*WikiTreeNode
+	// Err 获取子节点失败时的错误，此时 Children 为空
+	Err error
+}
+
+type wikiCrawler struct {
+	ctx     context.Context
+	client  *Client
+	options WikiCrawlOptions
+	sem     chan struct{}
+	wg      sync.WaitGroup
+
+	mu      sync.Mutex
+	visited map[string]bool
+}
+
+// CrawlWikiTree 并发遍历 roots 下的节点树，返回的树与逐个节点顺序遍历的结果相同
+// 快捷方式和已经出现过的节点不会展开，避免重复和循环
+func (c *Client) CrawlWikiTree(ctx context.Context, roots []*WikiNode, options WikiCrawlOptions) []*WikiTreeNode {
+	if options.Workers <= 0 {
+		options.Workers = DefaultCrawlWorkers
+	}
+	cr := &wikiCrawler{
+		ctx:     ctx,
+		client:  c,
+		options: options,
+		sem:     make(chan struct{}, options.Workers),
+		visited: map[string]bool{},
+	}
+	tree := make([]*WikiTreeNode, len(roots))
+	for i, root := range roots {
+		tree[i] = cr.add(root, 1)
+	}
+	cr.wg.Wait()
+	return tree
+}
+
+// 记录节点，需要时在后台获取它的子节点
+func (cr *wikiCrawler) add(node *WikiNode, depth int) *WikiTreeNode {
+	treeNode := &WikiTreeNode{WikiNode: node, Depth: depth}
+
+	cr.mu.Lock()
+	expand := !cr.visited[node.NodeToken] && node.HasChild && node.NodeType != WikiNodeTypeShortcut &&
+		(cr.options.MaxDepth <= 0 || depth < cr.options.MaxDepth)
+	cr.visited[node.NodeToken] = true
+	if cr.options.OnNode != nil {
+		cr.options.OnNode(treeNode)
+	}
+	cr.mu.Unlock()
+
+	if expand {
+		cr.wg.Add(1)
+		go cr.expand(treeNode)
+	}
+	return treeNode
+}
+
+func (cr *wikiCrawler) expand(treeNode *WikiTreeNode) {
+	defer cr.wg.Done()
+	select {
+	case cr.sem <- struct{}{}:
+	case <-cr.ctx.Done():
+		treeNode.Err = cr.ctx.Err()
+		return
+	}
+	children, err := cr.client.ListWikiNodes(cr.ctx, treeNode.SpaceID, treeNode.NodeToken)
+	<-cr.sem
+	if err != nil {
+		treeNode.Err = err
+		return
+	}
+
+	nodes := make([]*WikiTreeNode, len(children))
+	for i, child := range children {
+		nodes[i] = cr.add(child, treeNode.Depth+1)
+	}
+	treeNode.Children = nodes
+}
@@ -0,0 +1,128 @@
+package core_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type testWikiItem struct {
+	NodeToken       string `json:"node_token"`
+	Title           string `json:"title"`
+	ParentNodeToken string `json:"parent_node_token"`
+	NodeType        string `json:"node_type"`
+	OriginNodeToken string `json:"origin_node_token,omitempty"`
+	HasChild        bool   `json:"has_child"`
+}
+
+// 模拟知识空间的节点列表接口，children 以父节点 token 为键，空字符串为顶级节点
+func newWikiServer(children map[string][]testWikiItem, requested *[]string) *httptest.Server {
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/tenant_access_token/internal") {
+			fmt.Fprint(w, `{"code":0,"msg":"ok","tenant_access_token":"t-g1044abcdefghijk","expire":7200}`)
+			return
+		}
+		parent := r.URL.Query().Get("parent_node_token")
+		mu.Lock()
+		*requested = append(*requested, parent)
+		mu.Unlock()
+		if parent == "broken" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"code":131006,"msg":"permission denied"}`)
+			return
+		}
+		// 先请求的节点晚返回，打乱完成顺序
+		if len(children[parent]) > 1 {
+			time.Sleep(20 * time.Millisecond)
+		}
+		items := append([]testWikiItem(nil), children[parent]...)
+		for i := range items {
+			items[i].ParentNodeToken = parent
+		}
+		data, _ := json.Marshal(items)
+		fmt.Fprintf(w, `{"code":0,"msg":"ok","data":{"items":%s,"has_more":false}}`, data)
+	}))
+}
+
+func wikiTreeTitles(nodes []*core.WikiTreeNode) []string {
+	var titles []string
+	for _, node := range nodes {
+		title := node.Title
+		if len(node.Children) > 0 {
+			title += fmt.Sprintf("%v", wikiTreeTitles(node.Children))
+		}
+		titles = append(titles, title)
+	}
+	return titles
+}
+
+func TestCrawlWikiTree(t *testing.T) {
+	children := map[string][]testWikiItem{
+		"": {
+			{NodeToken: "a", Title: "A", HasChild: true},
+			{NodeToken: "b", Title: "B", HasChild: true},
+			{NodeToken: "c", Title: "C"},
+		},
+		"a": {
+			{NodeToken: "a1", Title: "A1", HasChild: true},
+			{NodeToken: "a2", Title: "A2"},
+		},
+		"a1":  {{NodeToken: "a11", Title: "A11", HasChild: true}},
+		"a11": {{NodeToken: "a111", Title: "A111"}},
+		"b": {
+			// 指向祖先节点的快捷方式不展开
+			{NodeToken: "s", Title: "S", NodeType: core.WikiNodeTypeShortcut, OriginNodeToken: "a", HasChild: true},
+			{NodeToken: "broken", Title: "Broken", HasChild: true},
+		},
+	}
+	var requested []string
+	server := newWikiServer(children, &requested)
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	ctx := context.Background()
+	roots, err := client.ListWikiNodes(ctx, "space", "")
+	assert.NoError(t, err)
+
+	var discovered []string
+	tree := client.CrawlWikiTree(ctx, roots, core.WikiCrawlOptions{
+		Workers: 4,
+		OnNode: func(node *core.WikiTreeNode) {
+			discovered = append(discovered, node.NodeToken)
+		},
+	})
+
+	assert.Equal(t, []string{"A[A1[A11[A111]] A2]", "B[S Broken]", "C"}, wikiTreeTitles(tree))
+	assert.Len(t, discovered, 9)
+	assert.Equal(t, 4, tree[0].Children[0].Children[0].Children[0].Depth)
+	assert.Equal(t, "b", tree[1].Children[1].ParentNodeToken)
+	assert.ErrorIs(t, tree[1].Children[1].Err, core.ErrPermissionDenied)
+	assert.ElementsMatch(t, []string{"", "a", "b", "a1", "a11", "broken"}, requested)
+}
+
+func TestCrawlWikiTreeMaxDepth(t *testing.T) {
+	children := map[string][]testWikiItem{
+		"a":  {{NodeToken: "a1", Title: "A1", HasChild: true}},
+		"a1": {{NodeToken: "a11", Title: "A11"}},
+	}
+	var requested []string
+	server := newWikiServer(children, &requested)
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	roots := []*core.WikiNode{{NodeToken: "a", Title: "A", SpaceID: "space", HasChild: true}}
+	tree := client.CrawlWikiTree(context.Background(), roots, core.WikiCrawlOptions{MaxDepth: 2})
+
+	assert.Equal(t, []string{"A[A1]"}, wikiTreeTitles(tree))
+	assert.Equal(t, []string{"a"}, requested)
+}
@@ -0,0 +1,392 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/Wsine/feishu2md/utils"
+	"github.com/chyroc/lark"
+)
+
+// DocumentNodeType 文档树节点类型
+type DocumentNodeType string
+
+const (
+	DocumentNodeSection   DocumentNodeType = "section"
+	DocumentNodeParagraph DocumentNodeType = "paragraph"
+	DocumentNodeList      DocumentNodeType = "list"
+	DocumentNodeListItem  DocumentNodeType = "list_item"
+	DocumentNodeCode      DocumentNodeType = "code"
+	DocumentNodeQuote     DocumentNodeType = "quote"
+	DocumentNodeEquation  DocumentNodeType = "equation"
+	DocumentNodeCallout   DocumentNodeType = "callout"
+	DocumentNodeTable     DocumentNodeType = "table"
+	DocumentNodeImage     DocumentNodeType = "image"
+	DocumentNodeFile      DocumentNodeType = "file"
+	DocumentNodeEmbed     DocumentNodeType = "embed"
+	DocumentNodeDivider   DocumentNodeType = "divider"
+	DocumentNodeGrid      DocumentNodeType = "grid"
+	DocumentNodeColumn    DocumentNodeType = "column"
+)
+
+// Document 与渲染格式无关的文档树，标题将正文划分为嵌套的章节
+type Document struct {
+	DocumentID  string                `json:"document_id"`
+	RevisionID  int64                 `json:"revision_id"`
+	Title       string                `json:"title"`
+	Children    []*DocumentNode       `json:"children"`
+	Diagnostics []*DocumentDiagnostic `json:"diagnostics,omitempty"`
+}
+
+// DocumentNode 文档树中的一个节点，BlockID 对应飞书文档中的块
+// 列表节点没有对应的块，由连续的列表项合并而来
+type DocumentNode struct {
+	Type     DocumentNodeType `json:"type"`
+	BlockID  string           `json:"block_id,omitempty"`
+	Level    int              `json:"level,omitempty"`    // 章节的标题级别
+	Heading  []*DocumentSpan  `json:"heading,omitempty"`  // 章节标题
+	Spans    []*DocumentSpan  `json:"spans,omitempty"`    // 段落、列表项、代码等的文本
+	Ordered  bool             `json:"ordered,omitempty"`  // 有序列表
+	Task     bool             `json:"task,omitempty"`     // 任务列表
+	Checked  bool             `json:"checked,omitempty"`  // 任务是否完成
+	Language string           `json:"language,omitempty"` // 代码块语言
+	Table    *DocumentTable   `json:"table,omitempty"`
+	Media    *DocumentMedia   `json:"media,omitempty"`
+	Children []*DocumentNode  `json:"children,omitempty"`
+}
+
+// DocumentSpan 带有样式的一段行内内容，Equation 为 true 时 Text 为公式源码
+type DocumentSpan struct {
+	Text          string `json:"text"`
+	Bold          bool   `json:"bold,omitempty"`
+	Italic        bool   `json:"italic,omitempty"`
+	Strikethrough bool   `json:"strikethrough,omitempty"`
+	Underline     bool   `json:"underline,omitempty"`
+	Code          bool   `json:"code,omitempty"`
+	Equation      bool   `json:"equation,omitempty"`
+	Link          string `json:"link,omitempty"`
+	MentionUser   string `json:"mention_user,omitempty"`
+	MentionDoc    string `json:"mention_doc,omitempty"`
+}
+
+// DocumentTable 表格，被合并的单元格只保留左上角的一个
+type DocumentTable struct {
+	Rows    int                  `json:"rows"`
+	Columns int                  `json:"columns"`
+	Cells   []*DocumentTableCell `json:"cells"`
+}
+
+type DocumentTableCell struct {
+	BlockID  string          `json:"block_id"`
+	Row      int             `json:"row"`
+	Column   int             `json:"column"`
+	RowSpan  int             `json:"row_span"`
+	ColSpan  int             `json:"col_span"`
+	Children []*DocumentNode `json:"children,omitempty"`
+}
+
+// DocumentMedia 图片、附件或内嵌的其他云文档
+type DocumentMedia struct {
+	Kind   string `json:"kind"`
+	Token  string `json:"token,omitempty"`
+	Name   string `json:"name,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Width  int64  `json:"width,omitempty"`
+	Height int64  `json:"height,omitempty"`
+}
+
+// DocumentDiagnostic 构建文档树时遇到的问题，例如缺失或不支持的块
+type DocumentDiagnostic struct {
+	BlockID  string `json:"block_id"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// BuildDocument 由文档块构建文档树
+func BuildDocument(doc *lark.DocxDocument, blocks []*lark.DocxBlock) *Document {
+	b := &documentBuilder{blockMap: make(map[string]*lark.DocxBlock, len(blocks))}
+	for _, block := range blocks {
+		b.blockMap[block.BlockID] = block
+	}
+
+	document := &Document{
+		DocumentID: doc.DocumentID,
+		RevisionID: doc.RevisionID,
+		Title:      doc.Title,
+		Children:   []*DocumentNode{},
+	}
+	if page, ok := b.blockMap[doc.DocumentID]; ok {
+		if page.Page != nil && document.Title == "" {
+			document.Title = documentPlainText(documentSpans(page.Page.Elements))
+		}
+		document.Children = b.buildChildren(page.Children)
+	} else {
+		b.diagnose(doc.DocumentID, "warning", "缺少文档根块")
+	}
+	document.Diagnostics = b.diagnostics
+	return document
+}
+
+// ToJSON 将文档树导出为 JSON
+func (d *Document) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+type documentBuilder struct {
+	blockMap    map[string]*lark.DocxBlock
+	diagnostics []*DocumentDiagnostic
+}
+
+func (b *documentBuilder) diagnose(blockID, severity, message string) {
+	b.diagnostics = append(b.diagnostics, &DocumentDiagnostic{
+		BlockID:  blockID,
+		Severity: severity,
+		Message:  message,
+	})
+}
+
+// 构建一组相邻的块：先合并连续的列表项，再按标题划分章节
+func (b *documentBuilder) buildChildren(ids []string) []*DocumentNode {
+	var nodes []*DocumentNode
+	for _, id := range ids {
+		block, ok := b.blockMap[id]
+		if !ok {
+			b.diagnose(id, "warning", "子块不存在")
+			continue
+		}
+		if node := b.buildBlock(block); node != nil {
+			nodes = append(nodes, node)
+		}
+	}
+	return groupDocumentSections(groupDocumentLists(nodes))
+}
+
+func (b *documentBuilder) buildBlock(block *lark.DocxBlock) *DocumentNode {
+	node := &DocumentNode{BlockID: block.BlockID}
+	switch block.BlockType {
+	case lark.DocxBlockTypeText:
+		node.Type = DocumentNodeParagraph
+		node.Spans = documentSpans(block.Text.Elements)
+	case lark.DocxBlockTypeHeading1, lark.DocxBlockTypeHeading2, lark.DocxBlockTypeHeading3,
+		lark.DocxBlockTypeHeading4, lark.DocxBlockTypeHeading5, lark.DocxBlockTypeHeading6,
+		lark.DocxBlockTypeHeading7, lark.DocxBlockTypeHeading8, lark.DocxBlockTypeHeading9:
+		level := int(block.BlockType-lark.DocxBlockTypeHeading1) + 1
+		text := reflect.ValueOf(block).Elem().FieldByName(fmt.Sprintf("Heading%d", level)).Interface().(*lark.DocxBlockText)
+		node.Type = DocumentNodeSection
+		node.Level = level
+		if text != nil {
+			node.Heading = documentSpans(text.Elements)
+		}
+	case lark.DocxBlockTypeBullet:
+		node.Type = DocumentNodeListItem
+		node.Spans = documentSpans(block.Bullet.Elements)
+	case lark.DocxBlockTypeOrdered:
+		node.Type = DocumentNodeListItem
+		node.Ordered = true
+		node.Spans = documentSpans(block.Ordered.Elements)
+	case lark.DocxBlockTypeTodo:
+		node.Type = DocumentNodeListItem
+		node.Task = true
+		node.Checked = block.Todo.Style != nil && block.Todo.Style.Done
+		node.Spans = documentSpans(block.Todo.Elements)
+	case lark.DocxBlockTypeCode:
+		node.Type = DocumentNodeCode
+		if block.Code.Style != nil {
+			node.Language = DocxCodeLang2MdStr[block.Code.Style.Language]
+		}
+		node.Spans = documentSpans(block.Code.Elements)
+	case lark.DocxBlockTypeQuote:
+		node.Type = DocumentNodeQuote
+		node.Spans = documentSpans(block.Quote.Elements)
+	case lark.DocxBlockTypeQuoteContainer:
+		node.Type = DocumentNodeQuote
+	case lark.DocxBlockTypeEquation:
+		node.Type = DocumentNodeEquation
+		node.Spans = documentSpans(block.Equation.Elements)
+	case lark.DocxBlockTypeCallout:
+		node.Type = DocumentNodeCallout
+	case lark.DocxBlockTypeDivider:
+		node.Type = DocumentNodeDivider
+	case lark.DocxBlockTypeImage:
+		node.Type = DocumentNodeImage
+		node.Media = &DocumentMedia{
+			Kind:   "image",
+			Token:  block.Image.Token,
+			Width:  block.Image.Width,
+			Height: block.Image.Height,
+		}
+	case lark.DocxBlockTypeFile:
+		node.Type = DocumentNodeFile
+		node.Media = &DocumentMedia{Kind: "file", Token: block.File.Token, Name: block.File.Name}
+	case lark.DocxBlockTypeView:
+		// 视图块只是文件块的容器
+		if len(block.Children) == 1 {
+			if child, ok := b.blockMap[block.Children[0]]; ok {
+				return b.buildBlock(child)
+			}
+		}
+		return nil
+	case lark.DocxBlockTypeSheet:
+		node.Type = DocumentNodeEmbed
+		node.Media = &DocumentMedia{Kind: "sheet", Token: block.Sheet.Token}
+	case lark.DocxBlockTypeBitable:
+		node.Type = DocumentNodeEmbed
+		node.Media = &DocumentMedia{Kind: "bitable", Token: block.Bitable.Token}
+	case lark.DocxBlockTypeMindnote:
+		node.Type = DocumentNodeEmbed
+		node.Media = &DocumentMedia{Kind: "mindnote", Token: block.Mindnote.Token}
+	case lark.DocxBlockTypeIframe:
+		node.Type = DocumentNodeEmbed
+		node.Media = &DocumentMedia{Kind: "iframe"}
+		if block.Iframe.Component != nil {
+			node.Media.URL = utils.UnescapeURL(block.Iframe.Component.URL)
+		}
+	case lark.DocxBlockTypeTable:
+		node.Type = DocumentNodeTable
+		node.Table = b.buildTable(block)
+		return node
+	case lark.DocxBlockTypeGrid:
+		node.Type = DocumentNodeGrid
+	case lark.DocxBlockTypeGridColumn:
+		node.Type = DocumentNodeColumn
+	default:
+		b.diagnose(block.BlockID, "info", fmt.Sprintf("不支持的块类型: %d", block.BlockType))
+		return nil
+	}
+	if len(block.Children) > 0 {
+		node.Children = b.buildChildren(block.Children)
+	}
+	return node
+}
+
+func (b *documentBuilder) buildTable(block *lark.DocxBlock) *DocumentTable {
+	table := &DocumentTable{Cells: []*DocumentTableCell{}}
+	if block.Table.Property == nil || block.Table.Property.ColumnSize == 0 {
+		b.diagnose(block.BlockID, "warning", "表格缺少行列信息")
+		return table
+	}
+	property := block.Table.Property
+	table.Rows = int(property.RowSize)
+	table.Columns = int(property.ColumnSize)
+	if len(block.Table.Cells) != table.Rows*table.Columns {
+		b.diagnose(block.BlockID, "warning", fmt.Sprintf("表格单元格数量 %d 与行列数 %dx%d 不一致",
+			len(block.Table.Cells), table.Rows, table.Columns))
+	}
+
+	covered := map[int]bool{}
+	for i, cellID := range block.Table.Cells {
+		if covered[i] {
+			continue
+		}
+		cell := &DocumentTableCell{
+			BlockID: cellID,
+			Row:     i / table.Columns,
+			Column:  i % table.Columns,
+			RowSpan: 1,
+			ColSpan: 1,
+		}
+		if i < len(property.MergeInfo) && property.MergeInfo[i] != nil {
+			if span := int(property.MergeInfo[i].RowSpan); span > 1 {
+				cell.RowSpan = span
+			}
+			if span := int(property.MergeInfo[i].ColSpan); span > 1 {
+				cell.ColSpan = span
+			}
+		}
+		for r := cell.Row; r < cell.Row+cell.RowSpan; r++ {
+			for c := cell.Column; c < cell.Column+cell.ColSpan; c++ {
+				covered[r*table.Columns+c] = true
+			}
+		}
+		if cellBlock, ok := b.blockMap[cellID]; ok {
+			cell.Children = b.buildChildren(cellBlock.Children)
+		} else {
+			b.diagnose(cellID, "warning", "单元格不存在")
+		}
+		table.Cells = append(table.Cells, cell)
+	}
+	return table
+}
+
+// 将类型相同的连续列表项合并为一个列表节点
+func groupDocumentLists(nodes []*DocumentNode) []*DocumentNode {
+	var result []*DocumentNode
+	var list *DocumentNode
+	for _, node := range nodes {
+		if node.Type != DocumentNodeListItem {
+			list = nil
+			result = append(result, node)
+			continue
+		}
+		if list == nil || list.Ordered != node.Ordered || list.Task != node.Task {
+			list = &DocumentNode{Type: DocumentNodeList, Ordered: node.Ordered, Task: node.Task}
+			result = append(result, list)
+		}
+		list.Children = append(list.Children, node)
+	}
+	return result
+}
+
+// 标题之后的块归入该标题的章节，直到遇到同级或更高级的标题
+func groupDocumentSections(nodes []*DocumentNode) []*DocumentNode {
+	result := []*DocumentNode{}
+	var stack []*DocumentNode
+	for _, node := range nodes {
+		if node.Type == DocumentNodeSection {
+			for len(stack) > 0 && stack[len(stack)-1].Level >= node.Level {
+				stack = stack[:len(stack)-1]
+			}
+		}
+		if len(stack) == 0 {
+			result = append(result, node)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+		}
+		if node.Type == DocumentNodeSection {
+			stack = append(stack, node)
+		}
+	}
+	return result
+}
+
+func documentSpans(elements []*lark.DocxTextElement) []*DocumentSpan {
+	var spans []*DocumentSpan
+	for _, e := range elements {
+		switch {
+		case e.TextRun != nil:
+			span := &DocumentSpan{Text: e.TextRun.Content}
+			if style := e.TextRun.TextElementStyle; style != nil {
+				span.Bold = style.Bold
+				span.Italic = style.Italic
+				span.Strikethrough = style.Strikethrough
+				span.Underline = style.Underline
+				span.Code = style.InlineCode
+				if style.Link != nil {
+					span.Link = utils.UnescapeURL(style.Link.URL)
+				}
+			}
+			spans = append(spans, span)
+		case e.Equation != nil:
+			spans = append(spans, &DocumentSpan{Text: e.Equation.Content, Equation: true})
+		case e.MentionUser != nil:
+			spans = append(spans, &DocumentSpan{Text: e.MentionUser.UserID, MentionUser: e.MentionUser.UserID})
+		case e.MentionDoc != nil:
+			spans = append(spans, &DocumentSpan{
+				Text:       e.MentionDoc.Title,
+				Link:       utils.UnescapeURL(e.MentionDoc.URL),
+				MentionDoc: e.MentionDoc.Token,
+			})
+		}
+	}
+	return spans
+}
+
+func documentPlainText(spans []*DocumentSpan) string {
+	text := ""
+	for _, span := range spans {
+		text += span.Text
+	}
+	return text
+}
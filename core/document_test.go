@@ -0,0 +1,87 @@
+package core_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/chyroc/lark"
+	"github.com/stretchr/testify/assert"
+)
+
+func docxText(content string) *lark.DocxBlockText {
+	return &lark.DocxBlockText{Elements: []*lark.DocxTextElement{
+		{TextRun: &lark.DocxTextElementTextRun{Content: content}},
+	}}
+}
+
+func TestBuildDocument(t *testing.T) {
+	doc := &lark.DocxDocument{DocumentID: "doc", Title: "标题"}
+	blocks := []*lark.DocxBlock{
+		{BlockID: "doc", BlockType: lark.DocxBlockTypePage, Page: docxText("标题"),
+			Children: []string{"intro", "h1", "p1", "b1", "b2", "h2", "o1", "h1b", "table", "missing"}},
+		{BlockID: "intro", BlockType: lark.DocxBlockTypeText, Text: docxText("前言")},
+		{BlockID: "h1", BlockType: lark.DocxBlockTypeHeading1, Heading1: docxText("第一章")},
+		{BlockID: "p1", BlockType: lark.DocxBlockTypeText, Text: &lark.DocxBlockText{Elements: []*lark.DocxTextElement{
+			{TextRun: &lark.DocxTextElementTextRun{Content: "链接", TextElementStyle: &lark.DocxTextElementStyle{
+				Bold: true, Link: &lark.DocxTextElementStyleLink{URL: "https%3A%2F%2Fexample.com"}}}},
+			{Equation: &lark.DocxTextElementEquation{Content: "x^2"}},
+		}}},
+		{BlockID: "b1", BlockType: lark.DocxBlockTypeBullet, Bullet: docxText("一"), Children: []string{"b1c"}},
+		{BlockID: "b1c", BlockType: lark.DocxBlockTypeBullet, Bullet: docxText("一.一")},
+		{BlockID: "b2", BlockType: lark.DocxBlockTypeBullet, Bullet: docxText("二")},
+		{BlockID: "h2", BlockType: lark.DocxBlockTypeHeading2, Heading2: docxText("1.1")},
+		{BlockID: "o1", BlockType: lark.DocxBlockTypeOrdered, Ordered: docxText("步骤")},
+		{BlockID: "h1b", BlockType: lark.DocxBlockTypeHeading1, Heading1: docxText("第二章")},
+		{BlockID: "table", BlockType: lark.DocxBlockTypeTable, Table: &lark.DocxBlockTable{
+			Cells: []string{"c1", "c2", "c3", "c4"},
+			Property: &lark.DocxBlockTableProperty{RowSize: 2, ColumnSize: 2, MergeInfo: []*lark.DocxBlockTablePropertyMergeInfo{
+				{RowSpan: 1, ColSpan: 2}, {RowSpan: 1, ColSpan: 1}, {RowSpan: 1, ColSpan: 1}, {RowSpan: 1, ColSpan: 1},
+			}},
+		}},
+		{BlockID: "c1", BlockType: lark.DocxBlockTypeTableCell, Children: []string{"c1t"}},
+		{BlockID: "c1t", BlockType: lark.DocxBlockTypeText, Text: docxText("合并")},
+		{BlockID: "c2", BlockType: lark.DocxBlockTypeTableCell},
+		{BlockID: "c3", BlockType: lark.DocxBlockTypeTableCell},
+		{BlockID: "c4", BlockType: lark.DocxBlockTypeTableCell},
+	}
+
+	document := core.BuildDocument(doc, blocks)
+	assert.Equal(t, "标题", document.Title)
+	assert.Len(t, document.Children, 3)
+	assert.Equal(t, core.DocumentNodeParagraph, document.Children[0].Type)
+
+	chapter := document.Children[1]
+	assert.Equal(t, core.DocumentNodeSection, chapter.Type)
+	assert.Equal(t, "h1", chapter.BlockID)
+	assert.Len(t, chapter.Children, 3)
+
+	paragraph := chapter.Children[0]
+	assert.Equal(t, "https://example.com", paragraph.Spans[0].Link)
+	assert.True(t, paragraph.Spans[0].Bold)
+	assert.True(t, paragraph.Spans[1].Equation)
+
+	list := chapter.Children[1]
+	assert.Equal(t, core.DocumentNodeList, list.Type)
+	assert.Len(t, list.Children, 2)
+	assert.Equal(t, core.DocumentNodeList, list.Children[0].Children[0].Type)
+
+	subsection := chapter.Children[2]
+	assert.Equal(t, 2, subsection.Level)
+	assert.True(t, subsection.Children[0].Ordered)
+
+	table := document.Children[2].Children[0].Table
+	assert.Len(t, table.Cells, 3)
+	assert.Equal(t, 2, table.Cells[0].ColSpan)
+	assert.Equal(t, "c3", table.Cells[1].BlockID)
+	assert.Equal(t, 1, table.Cells[1].Row)
+
+	assert.Len(t, document.Diagnostics, 1)
+	assert.Equal(t, "missing", document.Diagnostics[0].BlockID)
+
+	content, err := document.ToJSON()
+	assert.NoError(t, err)
+	var decoded core.Document
+	assert.NoError(t, json.Unmarshal(content, &decoded))
+	assert.Equal(t, "doc", decoded.DocumentID)
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/chyroc/lark"
+)
+
+// 云空间中的文件类型
+const (
+	DriveFileTypeFolder   = "folder"
+	DriveFileTypeDocx     = "docx"
+	DriveFileTypeDoc      = "doc"
+	DriveFileTypeSheet    = "sheet"
+	DriveFileTypeBitable  = "bitable"
+	DriveFileTypeMindnote = "mindnote"
+	DriveFileTypeFile     = "file"
+	DriveFileTypeShortcut = "shortcut"
+)
+
+// DriveFile 云空间文件夹中的文件，快捷方式已经解析为指向的文件
+type DriveFile struct {
+	Token    string
+	Name     string
+	Type     string
+	URL      string
+	Shortcut bool // 是否为快捷方式
+}
+
+// DriveTreeNode 文件夹树中的节点，只有文件夹有子节点
+type DriveTreeNode struct {
+	*DriveFile
+	Children []*DriveTreeNode
+	// Err 获取文件夹内容失败时的错误，此时 Children 为空
+	Err error
+}
+
+func newDriveFile(file *lark.GetDriveFileListRespFile) *DriveFile {
+	driveFile := &DriveFile{Token: file.Token, Name: file.Name, Type: file.Type, URL: file.URL}
+	if file.Type == DriveFileTypeShortcut && file.ShortcutInfo != nil {
+		driveFile.Token = file.ShortcutInfo.TargetToken
+		driveFile.Type = file.ShortcutInfo.TargetType
+		driveFile.Shortcut = true
+	}
+	return driveFile
+}
+
+// ListDriveFolder 获取文件夹中的所有文件，快捷方式解析为指向的文件
+func (c *Client) ListDriveFolder(ctx context.Context, folderToken string) ([]*DriveFile, error) {
+	files, err := c.GetDriveFolderFileList(ctx, nil, &folderToken)
+	if err != nil {
+		return nil, err
+	}
+	driveFiles := make([]*DriveFile, 0, len(files))
+	for _, file := range files {
+		driveFiles = append(driveFiles, newDriveFile(file))
+	}
+	return driveFiles, nil
+}
+
+// GetDriveFolderTree 递归获取文件夹中的文件和子文件夹
+// 指向文件夹的快捷方式不展开，避免重复导出和循环引用；单个子文件夹获取失败时记录在节点的 Err 中
+func (c *Client) GetDriveFolderTree(ctx context.Context, folderToken string) ([]*DriveTreeNode, error) {
+	files, err := c.ListDriveFolder(ctx, folderToken)
+	if err != nil {
+		return nil, err
+	}
+	visited := map[string]bool{folderToken: true}
+	return c.driveFolderChildren(ctx, files, visited), nil
+}
+
+func (c *Client) driveFolderChildren(ctx context.Context, files []*DriveFile, visited map[string]bool) []*DriveTreeNode {
+	nodes := make([]*DriveTreeNode, 0, len(files))
+	for _, file := range files {
+		node := &DriveTreeNode{DriveFile: file}
+		nodes = append(nodes, node)
+		if file.Type != DriveFileTypeFolder || file.Shortcut || visited[file.Token] {
+			continue
+		}
+		visited[file.Token] = true
+		children, err := c.ListDriveFolder(ctx, file.Token)
+		if err != nil {
+			node.Err = err
+			continue
+		}
+		node.Children = c.driveFolderChildren(ctx, children, visited)
+	}
+	return nodes
+}
+
+// DownloadDriveFile 下载云空间中的普通文件，返回文件名和内容
+func (c *Client) DownloadDriveFile(ctx context.Context, fileToken string) (string, []byte, error) {
+	resp, _, err := c.larkClient.Drive.DownloadDriveFile(ctx, &lark.DownloadDriveFileReq{
+		FileToken: fileToken,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	buf := new(bytes.Buffer)
+	if _, err := buf.ReadFrom(resp.File); err != nil {
+		return "", nil, err
+	}
+	return resp.Filename, buf.Bytes(), nil
+}
@@ -0,0 +1,64 @@
+package core_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDriveFolderTree(t *testing.T) {
+	folders := map[string][]map[string]interface{}{
+		"root": {
+			{"token": "doc1", "name": "Guide", "type": "docx"},
+			{"token": "sub", "name": "Sub", "type": "folder"},
+			{"token": "link", "name": "Root link", "type": "shortcut", "shortcut_info": map[string]string{"target_type": "folder", "target_token": "root"}},
+			{"token": "broken", "name": "Broken", "type": "folder"},
+		},
+		"sub": {
+			{"token": "sheet-link", "name": "Sheet", "type": "shortcut", "shortcut_info": map[string]string{"target_type": "sheet", "target_token": "sheet1"}},
+			{"token": "file1", "name": "a.pdf", "type": "file"},
+		},
+	}
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/tenant_access_token/internal") {
+			fmt.Fprint(w, `{"code":0,"msg":"ok","tenant_access_token":"t-g1044abcdefghijk","expire":7200}`)
+			return
+		}
+		folder := r.URL.Query().Get("folder_token")
+		requested = append(requested, folder)
+		if folder == "broken" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"code":1061004,"msg":"forbidden"}`)
+			return
+		}
+		data, _ := json.Marshal(folders[folder])
+		fmt.Fprintf(w, `{"code":0,"msg":"ok","data":{"files":%s,"has_more":false}}`, data)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	tree, err := client.GetDriveFolderTree(context.Background(), "root")
+	assert.NoError(t, err)
+
+	assert.Len(t, tree, 4)
+	assert.Equal(t, core.DriveFileTypeDocx, tree[0].Type)
+	sub := tree[1]
+	assert.Len(t, sub.Children, 2)
+	// 快捷方式解析为指向的文件
+	assert.Equal(t, "sheet1", sub.Children[0].Token)
+	assert.Equal(t, core.DriveFileTypeSheet, sub.Children[0].Type)
+	assert.True(t, sub.Children[0].Shortcut)
+	// 指向文件夹的快捷方式不展开
+	assert.True(t, tree[2].Shortcut)
+	assert.Empty(t, tree[2].Children)
+	assert.ErrorIs(t, tree[3].Err, core.ErrPermissionDenied)
+	assert.Equal(t, []string{"root", "sub", "broken"}, requested)
+}
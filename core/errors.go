@@ -0,0 +1,198 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chyroc/lark"
+)
+
+// 飞书接口错误的分类，使用 errors.Is 判断，例如 errors.Is(err, core.ErrNotFound)
+var (
+	ErrNotFound           = errors.New("文档不存在或已被删除")
+	ErrPermissionDenied   = errors.New("没有访问权限")
+	ErrRateLimited        = errors.New("请求过于频繁")
+	ErrInvalidCredentials = errors.New("应用凭证或用户令牌无效")
+	ErrTransient          = errors.New("飞书服务暂时不可用")
+)
+
+// APIError 飞书接口调用失败的详细信息，使用 errors.As 获取
+type APIError struct {
+	Kind       error         // 错误分类，无法识别时为 nil
+	Code       int64         // 飞书返回的错误码，没有时为 0
+	Msg        string        // 飞书返回的错误信息
+	HTTPStatus int           // HTTP 状态码，没有收到响应时为 0
+	RetryAfter time.Duration // 限流时建议的等待时间，未知时为 0
+	Err        error         // 原始错误
+}
+
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	if e.Code != 0 {
+		return fmt.Sprintf("%s (代码: %d)", e.Msg, e.Code)
+	}
+	return fmt.Sprintf("%s (状态码: %d)", e.Msg, e.HTTPStatus)
+}
+
+func (e *APIError) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter 返回错误建议的重试等待时间，未知时返回 0
+func RetryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// IsRetryable 限流和临时错误可以稍后重试
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrTransient)
+}
+
+// 飞书错误码到错误分类的映射，参考开放平台的通用错误码和各业务的错误码
+var codeKinds = map[int64]error{
+	// 通用
+	99991400: ErrRateLimited,
+	99991661: ErrInvalidCredentials,
+	99991663: ErrInvalidCredentials,
+	99991664: ErrInvalidCredentials,
+	99991668: ErrInvalidCredentials,
+	99991677: ErrInvalidCredentials,
+	99991672: ErrPermissionDenied,
+	99991679: ErrPermissionDenied,
+	10003:    ErrInvalidCredentials,
+	10014:    ErrInvalidCredentials,
+	20026:    ErrInvalidCredentials,
+	20037:    ErrInvalidCredentials,
+	// 云文档
+	1770002: ErrNotFound,
+	1770032: ErrPermissionDenied,
+	1061003: ErrNotFound,
+	1061007: ErrNotFound,
+	1061004: ErrPermissionDenied,
+	// 知识库
+	131005: ErrNotFound,
+	131006: ErrPermissionDenied,
+	// 多维表格
+	1254290: ErrRateLimited,
+	1254302: ErrPermissionDenied,
+}
+
+func kindOfCode(code int64, msg string) error {
+	if kind, ok := codeKinds[code]; ok {
+		return kind
+	}
+	if strings.Contains(strings.ToLower(msg), "frequency limit") {
+		return ErrRateLimited
+	}
+	return nil
+}
+
+func kindOfStatus(status int) error {
+	switch {
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status == http.StatusForbidden:
+		return ErrPermissionDenied
+	case status == http.StatusUnauthorized:
+		return ErrInvalidCredentials
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status == http.StatusRequestTimeout || status >= http.StatusInternalServerError:
+		return ErrTransient
+	}
+	return nil
+}
+
+// 网络超时、连接被重置等可以重试的错误
+func isTransientError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// 从响应头读取限流后的等待时间
+func retryAfterHeader(header http.Header) time.Duration {
+	for _, key := range []string{"Retry-After", "X-Ogw-Ratelimit-Reset"} {
+		if seconds, err := strconv.Atoi(header.Get(key)); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return 0
+}
+
+// 根据飞书返回的错误码和 HTTP 响应创建错误
+func newAPIError(code int64, msg string, resp *http.Response) *APIError {
+	e := &APIError{Code: code, Msg: msg}
+	if resp != nil {
+		e.HTTPStatus = resp.StatusCode
+		e.RetryAfter = retryAfterHeader(resp.Header)
+	}
+	e.Kind = kindOfCode(code, msg)
+	if e.Kind == nil {
+		e.Kind = kindOfStatus(e.HTTPStatus)
+	}
+	return e
+}
+
+// 将 SDK 返回的错误转换为 APIError
+func wrapAPIError(err error, resp *lark.Response) error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return err
+	}
+
+	e := &APIError{Err: err}
+	if resp != nil {
+		e.HTTPStatus = resp.StatusCode
+		e.RetryAfter = retryAfterHeader(resp.Header)
+	}
+	var larkErr *lark.Error
+	if errors.As(err, &larkErr) {
+		e.Code, e.Msg = larkErr.Code, larkErr.Msg
+		e.Kind = kindOfCode(e.Code, e.Msg)
+	}
+	if e.Kind == nil {
+		e.Kind = kindOfStatus(e.HTTPStatus)
+	}
+	if e.Kind == nil && errors.Is(err, ErrUserTokenExpired) {
+		e.Kind = ErrInvalidCredentials
+	}
+	if e.Kind == nil && isTransientError(err) {
+		e.Kind = ErrTransient
+	}
+	return e
+}
+
+// 为 SDK 请求返回的错误加上分类，凭证无效时清除缓存的租户令牌
+func (c *Client) errorMiddleware(next lark.ApiEndpoint) lark.ApiEndpoint {
+	return func(ctx context.Context, req *lark.RawRequestReq, resp interface{}) (*lark.Response, error) {
+		response, err := next(ctx, req, resp)
+		err = wrapAPIError(err, response)
+		c.invalidateTenantToken(err)
+		return response, err
+	}
+}
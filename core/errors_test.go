@@ -0,0 +1,131 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// 模拟飞书接口，除获取租户令牌外的请求都按照 respond 返回
+func newErrorServer(respond func(w http.ResponseWriter)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/tenant_access_token/internal") {
+			fmt.Fprint(w, `{"code":0,"msg":"ok","tenant_access_token":"t-g1044abcdefghijk","expire":7200}`)
+			return
+		}
+		respond(w)
+	}))
+}
+
+// 创建使用单独限流器的客户端，避免测试中的限流响应影响进程级的限流器
+func newTestClient(baseURL string, options ...core.ClientOption) *core.Client {
+	options = append([]core.ClientOption{
+		core.WithBaseURL(baseURL),
+		core.WithRateLimiter(core.NewRateLimiter(core.DefaultRateLimits)),
+	}, options...)
+	return core.NewClient("id", "secret", options...)
+}
+
+func TestAPIErrorClassification(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond func(w http.ResponseWriter)
+		kind    error
+		code    int64
+		status  int
+	}{
+		{
+			name: "not found",
+			respond: func(w http.ResponseWriter) {
+				fmt.Fprint(w, `{"code":1770002,"msg":"not found"}`)
+			},
+			kind: core.ErrNotFound,
+			code: 1770002,
+		},
+		{
+			name: "permission denied",
+			respond: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, `{"code":1770032,"msg":"forbidden"}`)
+			},
+			kind:   core.ErrPermissionDenied,
+			code:   1770032,
+			status: http.StatusForbidden,
+		},
+		{
+			name: "invalid credentials",
+			respond: func(w http.ResponseWriter) {
+				fmt.Fprint(w, `{"code":99991663,"msg":"Invalid access token for authorization"}`)
+			},
+			kind: core.ErrInvalidCredentials,
+			code: 99991663,
+		},
+		{
+			name: "server error without body",
+			respond: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			kind:   core.ErrTransient,
+			status: http.StatusBadGateway,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newErrorServer(tt.respond)
+			defer server.Close()
+
+			client := newTestClient(server.URL, core.WithRetryPolicy(core.RetryPolicy{}))
+			_, _, err := client.GetDocxContent(context.Background(), "doc")
+			assert.ErrorIs(t, err, tt.kind)
+			assert.False(t, errors.Is(err, core.ErrRateLimited))
+
+			var apiErr *core.APIError
+			if assert.ErrorAs(t, err, &apiErr) {
+				assert.Equal(t, tt.code, apiErr.Code)
+				if tt.status != 0 {
+					assert.Equal(t, tt.status, apiErr.HTTPStatus)
+				}
+			}
+		})
+	}
+}
+
+func TestAPIErrorRateLimited(t *testing.T) {
+	server := newErrorServer(func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"code":99991400,"msg":"request trigger frequency limit"}`)
+	})
+	defer server.Close()
+
+	client := newTestClient(server.URL, core.WithRetryPolicy(core.RetryPolicy{}))
+	_, _, err := client.GetDocxContent(context.Background(), "doc")
+	assert.ErrorIs(t, err, core.ErrRateLimited)
+	assert.True(t, core.IsRetryable(err))
+	assert.Equal(t, 3*time.Second, core.RetryAfter(err))
+
+	// 直接发送 HTTP 请求的接口也返回同样的错误分类
+	_, err = client.GetAllWikiSpaces(context.Background())
+	assert.ErrorIs(t, err, core.ErrRateLimited)
+	assert.Equal(t, 3*time.Second, core.RetryAfter(err))
+}
+
+func TestAPIErrorInvalidAppSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"code":10014,"msg":"app secret invalid"}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, core.WithRetryPolicy(core.RetryPolicy{}))
+	_, err := client.GetTenantAccessToken(context.Background())
+	assert.ErrorIs(t, err, core.ErrInvalidCredentials)
+	assert.False(t, core.IsRetryable(err))
+}
@@ -0,0 +1,177 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mozillazg/go-pinyin"
+)
+
+// 文件名模板中的变量
+const (
+	FilenameTitle     = "{title}"
+	FilenameToken     = "{token}"
+	FilenameNodeToken = "{node_token}"
+	FilenameDate      = "{date}"
+	FilenameIndex     = "{index}"
+)
+
+// DefaultFilenameMaxBytes 文件名（不含扩展名）的默认最大字节数，大多数文件系统限制为 255 字节
+const DefaultFilenameMaxBytes = 100
+
+// MinFilenameMaxBytes 文件名最大字节数的下限，保证添加重名后缀后仍然保留部分名称
+const MinFilenameMaxBytes = 8
+
+// FilenameOptions 导出文件的命名规则
+type FilenameOptions struct {
+	// Template 文件名模板，可以使用 {title}、{token}、{node_token}、{date}、{index}，为空时使用 {title}
+	Template string `json:"template"`
+	// Slugify 将文件名转换为小写字母、数字和短横线，中文转换为拼音
+	Slugify bool `json:"slugify"`
+	// MaxBytes 文件名（不含扩展名）的最大字节数，为 0 时使用 DefaultFilenameMaxBytes
+	MaxBytes int `json:"max_bytes"`
+}
+
+// FilenameFields 生成文件名时可以使用的文档信息
+type FilenameFields struct {
+	Title     string
+	Token     string
+	NodeToken string // 知识库节点的 token，不在知识库中时为空
+	Date      time.Time
+	Index     int // 文件在所在目录中的序号，从 1 开始
+}
+
+func (o FilenameOptions) Validate() error {
+	if o.MaxBytes != 0 && o.MaxBytes < MinFilenameMaxBytes {
+		return fmt.Errorf("文件名最大长度不能小于 %d: %d", MinFilenameMaxBytes, o.MaxBytes)
+	}
+	template := o.Template
+	for _, field := range []string{FilenameTitle, FilenameToken, FilenameNodeToken, FilenameDate, FilenameIndex} {
+		template = strings.ReplaceAll(template, field, "")
+	}
+	if strings.ContainsAny(template, "{}") {
+		return fmt.Errorf("文件名模板中有不支持的变量: %s", o.Template)
+	}
+	return nil
+}
+
+// ByteLimit 文件名（不含扩展名）的最大字节数，未经 Validate 的过小设置提高到 MinFilenameMaxBytes
+func (o FilenameOptions) ByteLimit() int {
+	if o.MaxBytes == 0 {
+		return DefaultFilenameMaxBytes
+	}
+	return max(o.MaxBytes, MinFilenameMaxBytes)
+}
+
+// Render 按照模板生成合法的文件名（不含扩展名），结果为空时使用 token
+func (o FilenameOptions) Render(fields FilenameFields) string {
+	template := o.Template
+	if template == "" {
+		template = FilenameTitle
+	}
+	date := ""
+	if !fields.Date.IsZero() {
+		date = fields.Date.Format("2006-01-02")
+	}
+	name := strings.NewReplacer(
+		FilenameTitle, fields.Title,
+		FilenameToken, fields.Token,
+		FilenameNodeToken, fields.NodeToken,
+		FilenameDate, date,
+		FilenameIndex, fmt.Sprintf("%d", fields.Index),
+	).Replace(template)
+	if o.Slugify {
+		name = Slugify(name)
+	}
+	name = SanitizeFilename(name, o.ByteLimit())
+	if name == "" || name == "_" {
+		name = SanitizeFilename(fields.Token, o.ByteLimit())
+	}
+	return name
+}
+
+// Windows 保留的设备名，即使带有扩展名也不能作为文件名
+var reservedFilenames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SanitizeFilename 替换文件名中的非法字符和控制字符，去掉 Windows 不允许的结尾的点和空格，
+// 为保留的设备名添加下划线，并按字符截断到 maxBytes 字节以内，maxBytes 为 0 时不截断
+func SanitizeFilename(name string, maxBytes int) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`\/:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if maxBytes > 0 {
+		name = TruncateBytes(name, maxBytes)
+	}
+	name = strings.TrimRight(name, ". ")
+	if name == "" {
+		return ""
+	}
+	base, _, _ := strings.Cut(name, ".")
+	if reservedFilenames[strings.ToUpper(strings.TrimSpace(base))] {
+		name = "_" + name
+		if maxBytes > 0 {
+			name = strings.TrimRight(TruncateBytes(name, maxBytes), ". ")
+		}
+	}
+	return name
+}
+
+// SuffixFilename 返回第 n 个同名文件的名称，n 大于 1 时添加 _n 后缀，截断名称使结果不超过 maxBytes 字节
+func SuffixFilename(base string, n, maxBytes int) string {
+	if n <= 1 {
+		return base
+	}
+	suffix := fmt.Sprintf("_%d", n)
+	return TruncateBytes(base, maxBytes-len(suffix)) + suffix
+}
+
+// TruncateBytes 截断字符串到 maxBytes 字节以内，不会截断 UTF-8 字符，maxBytes 小于 0 时返回空字符串
+func TruncateBytes(s string, maxBytes int) string {
+	maxBytes = max(maxBytes, 0)
+	if len(s) <= maxBytes {
+		return s
+	}
+	for maxBytes > 0 && !utf8.RuneStart(s[maxBytes]) {
+		maxBytes--
+	}
+	return s[:maxBytes]
+}
+
+var pinyinArgs = pinyin.NewArgs()
+
+// Slugify 转换为小写字母、数字和短横线组成的名称，汉字转换为不带声调的拼音
+func Slugify(s string) string {
+	var words []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			if p := pinyin.LazyPinyin(string(r), pinyinArgs); len(p) > 0 {
+				words = append(words, p[0])
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+	return strings.Join(words, "-")
+}
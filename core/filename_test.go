@@ -0,0 +1,105 @@
+package core_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilenameRender(t *testing.T) {
+	fields := core.FilenameFields{
+		Title:     "周报: 第 1 周",
+		Token:     "doxcnABC",
+		NodeToken: "wikcnXYZ",
+		Date:      time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC),
+		Index:     7,
+	}
+
+	assert.Equal(t, "周报_ 第 1 周", core.FilenameOptions{}.Render(fields))
+	assert.Equal(t, "2024-03-05_07_wikcnXYZ_doxcnABC", core.FilenameOptions{Template: "{date}_0{index}_{node_token}_{token}"}.Render(fields))
+	assert.Equal(t, "zhou-bao-di-1-zhou", core.FilenameOptions{Slugify: true}.Render(fields))
+
+	// 标题为空或只有非法字符时使用 token
+	assert.Equal(t, "doxcnABC", core.FilenameOptions{}.Render(core.FilenameFields{Token: "doxcnABC"}))
+	assert.Equal(t, "doxcnABC", core.FilenameOptions{Slugify: true}.Render(core.FilenameFields{Title: "!!!", Token: "doxcnABC"}))
+
+	// 截断不会破坏 UTF-8 字符
+	name := core.FilenameOptions{MaxBytes: 10}.Render(core.FilenameFields{Title: "飞书文档标题"})
+	assert.Equal(t, "飞书文", name)
+	assert.True(t, utf8.ValidString(name))
+}
+
+func TestFilenameValidate(t *testing.T) {
+	assert.NoError(t, core.FilenameOptions{}.Validate())
+	assert.NoError(t, core.FilenameOptions{Template: "{date}-{title}-{token}"}.Validate())
+	assert.Error(t, core.FilenameOptions{Template: "{name}"}.Validate())
+	assert.Error(t, core.FilenameOptions{MaxBytes: -1}.Validate())
+	assert.Error(t, core.FilenameOptions{MaxBytes: 2}.Validate())
+	assert.NoError(t, core.FilenameOptions{MaxBytes: core.MinFilenameMaxBytes}.Validate())
+	assert.Equal(t, core.DefaultFilenameMaxBytes, core.FilenameOptions{}.ByteLimit())
+	assert.Equal(t, 20, core.FilenameOptions{MaxBytes: 20}.ByteLimit())
+	assert.Equal(t, core.MinFilenameMaxBytes, core.FilenameOptions{MaxBytes: 1}.ByteLimit())
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	assert.Equal(t, "a_b_c_d_e_f_g_h_i", core.SanitizeFilename(`a\b/c:d*e?f"g<h>i`, 0))
+	assert.Equal(t, "tab_name", core.SanitizeFilename("tab\tname", 0))
+	assert.Equal(t, "trailing", core.SanitizeFilename(" trailing. . ", 0))
+	assert.Equal(t, "_CON", core.SanitizeFilename("CON", 0))
+	assert.Equal(t, "_nul.txt", core.SanitizeFilename("nul.txt", 0))
+	assert.Equal(t, "CONSOLE", core.SanitizeFilename("CONSOLE", 0))
+	assert.Equal(t, "", core.SanitizeFilename("...", 0))
+
+	long := core.SanitizeFilename(strings.Repeat("文", 50), core.DefaultFilenameMaxBytes)
+	assert.LessOrEqual(t, len(long), core.DefaultFilenameMaxBytes)
+	assert.True(t, utf8.ValidString(long))
+}
+
+func TestTruncateBytes(t *testing.T) {
+	assert.Equal(t, "abc", core.TruncateBytes("abc", 5))
+	assert.Equal(t, "ab", core.TruncateBytes("abc", 2))
+	assert.Equal(t, "a", core.TruncateBytes("a飞", 3))
+	assert.Equal(t, "a飞", core.TruncateBytes("a飞", 4))
+	assert.Equal(t, "", core.TruncateBytes("飞", 2))
+	assert.Equal(t, "", core.TruncateBytes("abc", -1))
+}
+
+func TestSuffixFilename(t *testing.T) {
+	assert.Equal(t, "飞书文档", core.SuffixFilename("飞书文档", 1, 100))
+	assert.Equal(t, "飞书文档_2", core.SuffixFilename("飞书文档", 2, 100))
+	// 加上后缀超过限制时按字符截断名称
+	assert.Equal(t, "飞书_12", core.SuffixFilename("飞书文档", 12, 9))
+	// 限制小于后缀长度时不会越界
+	assert.Equal(t, "_2", core.SuffixFilename("abc", 2, 1))
+	assert.Equal(t, "_10", core.SuffixFilename("abc", 10, 2))
+
+	// 过小的设置提高到下限后，重名文件仍然保留部分名称
+	limit := core.FilenameOptions{MaxBytes: 1}.ByteLimit()
+	name := core.FilenameOptions{MaxBytes: 1}.Render(core.FilenameFields{Title: "abcdefghij"})
+	assert.Equal(t, "abcdef_2", core.SuffixFilename(name, 2, limit))
+}
+
+func TestSlugify(t *testing.T) {
+	assert.Equal(t, "fei-shu-api-wen-dang", core.Slugify("飞书 API 文档"))
+	assert.Equal(t, "hello-world-2024", core.Slugify("Hello, World! 2024"))
+	assert.Equal(t, "v2-ban-ben", core.Slugify("V2版本"))
+	assert.Equal(t, "", core.Slugify("---"))
+}
+
+func TestSyncStatePathOwner(t *testing.T) {
+	dir := t.TempDir()
+	state, err := core.OpenSyncState(dir)
+	assert.NoError(t, err)
+
+	md := writeTestFile(t, filepath.Join(dir, "A", "Guide.md"), "guide")
+	assert.NoError(t, state.Record("doc", core.SyncRecord{Path: md}))
+
+	assert.Equal(t, "doc", state.PathOwner(md))
+	assert.Equal(t, "doc", state.PathOwner(filepath.Join(dir, "a", "guide.md")))
+	assert.Equal(t, "", state.PathOwner(filepath.Join(dir, "A", "Other.md")))
+}
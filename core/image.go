@@ -0,0 +1,134 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// 图片处理后的格式，为空时保持原格式
+const (
+	ImageFormatJPEG = "jpeg"
+	ImageFormatPNG  = "png"
+	ImageFormatWebP = "webp"
+)
+
+// DefaultJPEGQuality 未指定压缩质量时 JPEG 使用的质量
+const DefaultJPEGQuality = 85
+
+// ImageOptions 下载图片后的处理选项，零值表示不处理
+type ImageOptions struct {
+	Format   string `json:"format"`    // 转换后的格式：jpeg、png 或 webp（无损），为空时保持原格式
+	MaxWidth int    `json:"max_width"` // 宽度超过时等比例缩小，0 表示不限制
+	Quality  int    `json:"quality"`   // JPEG 压缩质量 1-100，0 表示使用默认值
+}
+
+// Enabled 是否需要处理图片
+func (o ImageOptions) Enabled() bool {
+	return o.Format != "" || o.MaxWidth > 0 || o.Quality > 0
+}
+
+// Validate 检查选项是否有效
+func (o ImageOptions) Validate() error {
+	switch o.Format {
+	case "", ImageFormatJPEG, ImageFormatPNG, ImageFormatWebP:
+	default:
+		return fmt.Errorf("不支持的图片格式: %s", o.Format)
+	}
+	if o.MaxWidth < 0 {
+		return fmt.Errorf("无效的最大宽度: %d", o.MaxWidth)
+	}
+	if o.Quality < 0 || o.Quality > 100 {
+		return fmt.Errorf("无效的压缩质量: %d", o.Quality)
+	}
+	return nil
+}
+
+// ProcessImage 按照选项转换格式、缩小尺寸并重新压缩，返回处理后的数据和扩展名
+// 无法解码的图片（如 SVG）和 GIF 动图保持原样；只重新压缩时，结果比原图大则保留原图
+func ProcessImage(data []byte, ext string, options ImageOptions) ([]byte, string, error) {
+	if !options.Enabled() {
+		return data, ext, nil
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil || format == "gif" {
+		return data, ext, nil
+	}
+
+	resized := false
+	if bounds := img.Bounds(); options.MaxWidth > 0 && bounds.Dx() > options.MaxWidth {
+		height := max(bounds.Dy()*options.MaxWidth/bounds.Dx(), 1)
+		dst := image.NewRGBA(image.Rect(0, 0, options.MaxWidth, height))
+		xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, xdraw.Over, nil)
+		img = dst
+		resized = true
+	}
+
+	target := options.Format
+	if target == "" {
+		target = format
+	}
+	buf := new(bytes.Buffer)
+	switch target {
+	case ImageFormatJPEG:
+		quality := options.Quality
+		if quality == 0 {
+			quality = DefaultJPEGQuality
+		}
+		err = jpeg.Encode(buf, flatten(img), &jpeg.Options{Quality: quality})
+		ext = ".jpg"
+	case ImageFormatPNG:
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		err = encoder.Encode(buf, img)
+		ext = ".png"
+	case ImageFormatWebP:
+		err = nativewebp.Encode(buf, img, nil)
+		ext = ".webp"
+	default:
+		return data, ext, nil
+	}
+	if err != nil {
+		return nil, ext, fmt.Errorf("转换图片为 %s 失败: %w", target, err)
+	}
+
+	if options.Format == "" && !resized && buf.Len() >= len(data) {
+		return data, ext, nil
+	}
+	return buf.Bytes(), ext, nil
+}
+
+// JPEG 不支持透明度，透明区域填充为白色
+func flatten(img image.Image) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
+
+// ProcessImage 按照选项处理下载的图片，并更新保存路径的扩展名
+func (r *AssetResult) ProcessImage(options ImageOptions) error {
+	if r.Err != nil || !options.Enabled() {
+		return r.Err
+	}
+	oldExt := filepath.Ext(r.Path)
+	data, ext, err := ProcessImage(r.Data, oldExt, options)
+	if err != nil {
+		return err
+	}
+	r.Data = data
+	r.Path = strings.TrimSuffix(r.Path, oldExt) + ext
+	return nil
+}
@@ -0,0 +1,93 @@
+package core_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+	_ "golang.org/x/image/webp"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.NRGBA{uint8(x), uint8(y), 128, 200})
+		}
+	}
+	buf := new(bytes.Buffer)
+	assert.NoError(t, png.Encode(buf, img))
+	return buf.Bytes()
+}
+
+func TestProcessImage(t *testing.T) {
+	data := testPNG(t, 200, 100)
+
+	// 零值选项不处理
+	out, ext, err := core.ProcessImage(data, ".png", core.ImageOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, data, out)
+	assert.Equal(t, ".png", ext)
+
+	// 缩小宽度并保持比例
+	out, ext, err = core.ProcessImage(data, ".png", core.ImageOptions{MaxWidth: 50})
+	assert.NoError(t, err)
+	assert.Equal(t, ".png", ext)
+	config, format, err := image.DecodeConfig(bytes.NewReader(out))
+	assert.NoError(t, err)
+	assert.Equal(t, "png", format)
+	assert.Equal(t, 50, config.Width)
+	assert.Equal(t, 25, config.Height)
+
+	// 比最大宽度窄的图片不放大
+	out, _, err = core.ProcessImage(data, ".png", core.ImageOptions{MaxWidth: 400, Format: core.ImageFormatJPEG, Quality: 60})
+	assert.NoError(t, err)
+	config, format, err = image.DecodeConfig(bytes.NewReader(out))
+	assert.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, 200, config.Width)
+
+	out, ext, err = core.ProcessImage(data, ".png", core.ImageOptions{Format: core.ImageFormatWebP})
+	assert.NoError(t, err)
+	assert.Equal(t, ".webp", ext)
+	_, format, err = image.Decode(bytes.NewReader(out))
+	assert.NoError(t, err)
+	assert.Equal(t, "webp", format)
+
+	// 无法解码的图片保持原样
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`)
+	out, ext, err = core.ProcessImage(svg, ".svg", core.ImageOptions{Format: core.ImageFormatJPEG})
+	assert.NoError(t, err)
+	assert.Equal(t, svg, out)
+	assert.Equal(t, ".svg", ext)
+}
+
+func TestProcessImageKeepsSmallerOriginal(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	buf := new(bytes.Buffer)
+	assert.NoError(t, jpeg.Encode(buf, img, &jpeg.Options{Quality: 10}))
+
+	// 只重新压缩时结果更大，保留原图
+	out, ext, err := core.ProcessImage(buf.Bytes(), ".jpg", core.ImageOptions{Quality: 95})
+	assert.NoError(t, err)
+	assert.Equal(t, buf.Bytes(), out)
+	assert.Equal(t, ".jpg", ext)
+}
+
+func TestAssetResultProcessImage(t *testing.T) {
+	asset := &core.AssetResult{Token: "img", Path: "images/img.png", Data: testPNG(t, 20, 20)}
+	assert.NoError(t, asset.ProcessImage(core.ImageOptions{Format: core.ImageFormatJPEG}))
+	assert.Equal(t, "images/img.jpg", asset.Path)
+	_, format, err := image.DecodeConfig(bytes.NewReader(asset.Data))
+	assert.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+
+	assert.Error(t, core.ImageOptions{Format: "bmp"}.Validate())
+	assert.Error(t, core.ImageOptions{Quality: 101}.Validate())
+	assert.NoError(t, core.ImageOptions{Format: core.ImageFormatWebP, MaxWidth: 800}.Validate())
+}
@@ -0,0 +1,801 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/88250/lute/ast"
+	"github.com/88250/lute/parse"
+	"github.com/chyroc/lark"
+)
+
+// 单次创建子块的数量上限
+const docxChildrenPerRequest = 50
+
+// MaxImportImageSize 导入时单张图片的大小上限，与飞书云文档素材上传接口的 20MB 限制一致
+const MaxImportImageSize = 20 << 20
+
+// ImportBlock 导入时待创建的文档块
+type ImportBlock struct {
+	Block    *lark.DocxBlock
+	Children []*ImportBlock
+	// ImageSrc 图片块的来源，可以是本地路径或 http 链接，创建空图片块后再上传
+	ImageSrc string
+	// Cells 表格中每个单元格的文本，按行排列
+	Cells [][][]*lark.DocxTextElement
+}
+
+// ImportOptions 导入 Markdown 的目标位置
+// 指定 WikiSpaceID 时在知识库中创建节点，否则在 FolderToken 对应的文件夹中创建文档
+type ImportOptions struct {
+	Title               string
+	FolderToken         string
+	WikiSpaceID         string
+	WikiParentNodeToken string
+	// BaseDir 用于解析 Markdown 中的相对图片路径，图片需要位于该目录中，为空时不上传本地图片
+	BaseDir string
+}
+
+// ImportResult 导入后生成的文档
+type ImportResult struct {
+	DocumentID string `json:"document_id"`
+	NodeToken  string `json:"node_token,omitempty"`
+	Title      string `json:"title"`
+}
+
+// MdStr2DocxCodeLang 由 Markdown 代码块的语言标识得到文档代码块语言，是 DocxCodeLang2MdStr 的逆映射
+func MdStr2DocxCodeLang(lang string) lark.DocxCodeLanguage {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" {
+		return lark.DocxCodeLanguagePlainText
+	}
+	for codeLang, str := range DocxCodeLang2MdStr {
+		if str == lang {
+			return codeLang
+		}
+	}
+	if codeLang, ok := mdCodeLangAliases[lang]; ok {
+		return codeLang
+	}
+	return lark.DocxCodeLanguagePlainText
+}
+
+// 常见的代码块语言别名
+var mdCodeLangAliases = map[string]lark.DocxCodeLanguage{
+	"sh":          lark.DocxCodeLanguageShell,
+	"zsh":         lark.DocxCodeLanguageShell,
+	"console":     lark.DocxCodeLanguageShell,
+	"golang":      lark.DocxCodeLanguageGo,
+	"js":          lark.DocxCodeLanguageJavaScript,
+	"jsx":         lark.DocxCodeLanguageJavaScript,
+	"ts":          lark.DocxCodeLanguageTypeScript,
+	"tsx":         lark.DocxCodeLanguageTypeScript,
+	"py":          lark.DocxCodeLanguagePython,
+	"python3":     lark.DocxCodeLanguagePython,
+	"rb":          lark.DocxCodeLanguageRuby,
+	"rs":          lark.DocxCodeLanguageRust,
+	"c++":         lark.DocxCodeLanguageCPlusPlus,
+	"cc":          lark.DocxCodeLanguageCPlusPlus,
+	"cs":          lark.DocxCodeLanguageCSharp,
+	"c#":          lark.DocxCodeLanguageCSharp,
+	"objc":        lark.DocxCodeLanguageObjective,
+	"objective-c": lark.DocxCodeLanguageObjective,
+	"kt":          lark.DocxCodeLanguageKotlin,
+	"yml":         lark.DocxCodeLanguageYAML,
+	"md":          lark.DocxCodeLanguageMarkdown,
+	"tex":         lark.DocxCodeLanguageLateX,
+	"ps1":         lark.DocxCodeLanguagePower,
+	"proto":       lark.DocxCodeLanguageProtoBuf,
+	"vb":          lark.DocxCodeLanguageVisual,
+	"text":        lark.DocxCodeLanguagePlainText,
+	"plaintext":   lark.DocxCodeLanguagePlainText,
+}
+
+// ParseMarkdownToBlocks 将 Markdown 解析为待创建的文档块
+func ParseMarkdownToBlocks(markdown string) []*ImportBlock {
+	tree := parse.Parse("", []byte(markdown), parse.NewOptions())
+	return convertMarkdownBlocks(tree.Root)
+}
+
+func convertMarkdownBlocks(parent *ast.Node) []*ImportBlock {
+	var blocks []*ImportBlock
+	for n := parent.FirstChild; n != nil; n = n.Next {
+		blocks = append(blocks, convertMarkdownBlock(n)...)
+	}
+	return blocks
+}
+
+func convertMarkdownBlock(n *ast.Node) []*ImportBlock {
+	switch n.Type {
+	case ast.NodeHeading:
+		level := n.HeadingLevel
+		if level < 1 || level > 6 {
+			level = 1
+		}
+		text := &lark.DocxBlockText{Elements: convertMarkdownInlines(n, &lark.DocxTextElementStyle{})}
+		block := &lark.DocxBlock{BlockType: lark.DocxBlockTypeHeading1 + lark.DocxBlockType(level-1)}
+		switch level {
+		case 1:
+			block.Heading1 = text
+		case 2:
+			block.Heading2 = text
+		case 3:
+			block.Heading3 = text
+		case 4:
+			block.Heading4 = text
+		case 5:
+			block.Heading5 = text
+		case 6:
+			block.Heading6 = text
+		}
+		return []*ImportBlock{{Block: block}}
+	case ast.NodeParagraph:
+		return convertMarkdownParagraph(n)
+	case ast.NodeThematicBreak:
+		return []*ImportBlock{{Block: &lark.DocxBlock{BlockType: lark.DocxBlockTypeDivider}}}
+	case ast.NodeCodeBlock:
+		code := ""
+		if content := n.ChildByType(ast.NodeCodeBlockCode); content != nil {
+			code = strings.TrimSuffix(string(content.Tokens), "\n")
+		}
+		lang := strings.Fields(string(n.CodeBlockInfo))
+		info := ""
+		if len(lang) > 0 {
+			info = lang[0]
+		}
+		return []*ImportBlock{{Block: &lark.DocxBlock{
+			BlockType: lark.DocxBlockTypeCode,
+			Code: &lark.DocxBlockText{
+				Style:    &lark.DocxTextStyle{Language: MdStr2DocxCodeLang(info)},
+				Elements: textElements(code),
+			},
+		}}}
+	case ast.NodeMathBlock:
+		content := ""
+		if c := n.ChildByType(ast.NodeMathBlockContent); c != nil {
+			content = strings.TrimSpace(string(c.Tokens))
+		}
+		// 公式块不支持通过接口创建，使用只包含公式的文本块代替
+		return []*ImportBlock{{Block: &lark.DocxBlock{
+			BlockType: lark.DocxBlockTypeText,
+			Text: &lark.DocxBlockText{Elements: []*lark.DocxTextElement{
+				{Equation: &lark.DocxTextElementEquation{Content: content}},
+			}},
+		}}}
+	case ast.NodeBlockquote:
+		// 引用中的段落转为引用块，其他内容按普通块处理
+		var blocks []*ImportBlock
+		for c := n.FirstChild; c != nil; c = c.Next {
+			if c.Type != ast.NodeParagraph {
+				blocks = append(blocks, convertMarkdownBlock(c)...)
+				continue
+			}
+			blocks = append(blocks, &ImportBlock{Block: &lark.DocxBlock{
+				BlockType: lark.DocxBlockTypeQuote,
+				Quote:     &lark.DocxBlockText{Elements: convertMarkdownInlines(c, &lark.DocxTextElementStyle{})},
+			}})
+		}
+		return blocks
+	case ast.NodeList:
+		var blocks []*ImportBlock
+		for item := n.FirstChild; item != nil; item = item.Next {
+			if item.Type == ast.NodeListItem {
+				blocks = append(blocks, convertMarkdownListItem(item))
+			}
+		}
+		return blocks
+	case ast.NodeTable:
+		return []*ImportBlock{convertMarkdownTable(n)}
+	case ast.NodeHTMLBlock:
+		content := strings.TrimSpace(string(n.Tokens))
+		if content == "" {
+			return nil
+		}
+		return []*ImportBlock{{Block: &lark.DocxBlock{
+			BlockType: lark.DocxBlockTypeText,
+			Text:      &lark.DocxBlockText{Elements: textElements(content)},
+		}}}
+	}
+	return nil
+}
+
+// 段落中的图片单独成块，图片前后的文字分别生成文本块
+func convertMarkdownParagraph(n *ast.Node) []*ImportBlock {
+	var blocks []*ImportBlock
+	var elements []*lark.DocxTextElement
+	flush := func() {
+		if hasVisibleText(elements) {
+			blocks = append(blocks, &ImportBlock{Block: &lark.DocxBlock{
+				BlockType: lark.DocxBlockTypeText,
+				Text:      &lark.DocxBlockText{Elements: elements},
+			}})
+		}
+		elements = nil
+	}
+	for c := n.FirstChild; c != nil; c = c.Next {
+		if c.Type == ast.NodeImage {
+			flush()
+			blocks = append(blocks, &ImportBlock{
+				Block:    &lark.DocxBlock{BlockType: lark.DocxBlockTypeImage, Image: &lark.DocxBlockImage{}},
+				ImageSrc: markdownLinkDest(c),
+			})
+			continue
+		}
+		elements = appendTextElements(elements, convertMarkdownInline(c, &lark.DocxTextElementStyle{})...)
+	}
+	flush()
+	return blocks
+}
+
+func convertMarkdownListItem(item *ast.Node) *ImportBlock {
+	block := &lark.DocxBlock{}
+	text := &lark.DocxBlockText{}
+	switch {
+	case item.ListData.Typ == 3:
+		block.BlockType = lark.DocxBlockTypeTodo
+		block.Todo = text
+		text.Style = &lark.DocxTextStyle{Done: item.ListData.Checked}
+	case item.ListData.Typ == 1:
+		block.BlockType = lark.DocxBlockTypeOrdered
+		block.Ordered = text
+	default:
+		block.BlockType = lark.DocxBlockTypeBullet
+		block.Bullet = text
+	}
+
+	// 第一个段落作为列表项的文本，其余内容作为子块
+	result := &ImportBlock{Block: block}
+	child := item.FirstChild
+	if child != nil && child.Type == ast.NodeParagraph {
+		text.Elements = convertMarkdownInlines(child, &lark.DocxTextElementStyle{})
+		child = child.Next
+	}
+	for ; child != nil; child = child.Next {
+		result.Children = append(result.Children, convertMarkdownBlock(child)...)
+	}
+	if len(text.Elements) == 0 {
+		text.Elements = textElements("")
+	}
+	return result
+}
+
+func convertMarkdownTable(n *ast.Node) *ImportBlock {
+	var rows [][][]*lark.DocxTextElement
+	appendRow := func(row *ast.Node) {
+		var cells [][]*lark.DocxTextElement
+		for cell := row.FirstChild; cell != nil; cell = cell.Next {
+			if cell.Type == ast.NodeTableCell {
+				cells = append(cells, convertMarkdownInlines(cell, &lark.DocxTextElementStyle{}))
+			}
+		}
+		rows = append(rows, cells)
+	}
+	for c := n.FirstChild; c != nil; c = c.Next {
+		switch c.Type {
+		case ast.NodeTableHead:
+			for row := c.FirstChild; row != nil; row = row.Next {
+				appendRow(row)
+			}
+		case ast.NodeTableRow:
+			appendRow(c)
+		}
+	}
+
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	for i := range rows {
+		for len(rows[i]) < columns {
+			rows[i] = append(rows[i], nil)
+		}
+	}
+	return &ImportBlock{
+		Block: &lark.DocxBlock{
+			BlockType: lark.DocxBlockTypeTable,
+			Table: &lark.DocxBlockTable{Property: &lark.DocxBlockTableProperty{
+				RowSize:    int64(len(rows)),
+				ColumnSize: int64(columns),
+			}},
+		},
+		Cells: rows,
+	}
+}
+
+func convertMarkdownInlines(n *ast.Node, style *lark.DocxTextElementStyle) []*lark.DocxTextElement {
+	var elements []*lark.DocxTextElement
+	for c := n.FirstChild; c != nil; c = c.Next {
+		elements = appendTextElements(elements, convertMarkdownInline(c, style)...)
+	}
+	return elements
+}
+
+func convertMarkdownInline(n *ast.Node, style *lark.DocxTextElementStyle) []*lark.DocxTextElement {
+	newStyle := *style
+	switch n.Type {
+	case ast.NodeText:
+		return []*lark.DocxTextElement{textRun(string(n.Tokens), style)}
+	case ast.NodeSoftBreak:
+		return []*lark.DocxTextElement{textRun(" ", style)}
+	case ast.NodeHardBreak, ast.NodeBr:
+		return []*lark.DocxTextElement{textRun("\n", style)}
+	case ast.NodeEmphasis:
+		newStyle.Italic = true
+		return convertMarkdownInlines(n, &newStyle)
+	case ast.NodeStrong:
+		newStyle.Bold = true
+		return convertMarkdownInlines(n, &newStyle)
+	case ast.NodeStrikethrough:
+		newStyle.Strikethrough = true
+		return convertMarkdownInlines(n, &newStyle)
+	case ast.NodeUnderline:
+		newStyle.Underline = true
+		return convertMarkdownInlines(n, &newStyle)
+	case ast.NodeCodeSpan:
+		newStyle.InlineCode = true
+		content := ""
+		if c := n.ChildByType(ast.NodeCodeSpanContent); c != nil {
+			content = string(c.Tokens)
+		}
+		return []*lark.DocxTextElement{textRun(content, &newStyle)}
+	case ast.NodeInlineMath:
+		content := ""
+		if c := n.ChildByType(ast.NodeInlineMathContent); c != nil {
+			content = string(c.Tokens)
+		}
+		return []*lark.DocxTextElement{{Equation: &lark.DocxTextElementEquation{Content: content}}}
+	case ast.NodeLink:
+		dest := markdownLinkDest(n)
+		if dest != "" {
+			newStyle.Link = &lark.DocxTextElementStyleLink{URL: url.QueryEscape(dest)}
+		}
+		var elements []*lark.DocxTextElement
+		for c := n.FirstChild; c != nil; c = c.Next {
+			switch c.Type {
+			case ast.NodeLinkText:
+				elements = appendTextElements(elements, textRun(string(c.Tokens), &newStyle))
+			case ast.NodeOpenBracket, ast.NodeCloseBracket, ast.NodeOpenParen, ast.NodeCloseParen,
+				ast.NodeLinkDest, ast.NodeLinkSpace, ast.NodeLinkTitle, ast.NodeBang:
+			default:
+				elements = appendTextElements(elements, convertMarkdownInline(c, &newStyle)...)
+			}
+		}
+		if len(elements) == 0 {
+			elements = []*lark.DocxTextElement{textRun(dest, &newStyle)}
+		}
+		return elements
+	case ast.NodeImage:
+		// 行内图片无法放入文本块，保留替代文本
+		var alt []string
+		for c := n.FirstChild; c != nil; c = c.Next {
+			if c.Type == ast.NodeLinkText {
+				alt = append(alt, string(c.Tokens))
+			}
+		}
+		return []*lark.DocxTextElement{textRun(strings.Join(alt, ""), style)}
+	case ast.NodeHTMLEntity:
+		return []*lark.DocxTextElement{textRun(html.UnescapeString(string(n.Tokens)), style)}
+	case ast.NodeBackslash:
+		if c := n.ChildByType(ast.NodeBackslashContent); c != nil {
+			return []*lark.DocxTextElement{textRun(string(c.Tokens), style)}
+		}
+		return nil
+	case ast.NodeEmoji:
+		if c := n.FirstChild; c != nil {
+			return []*lark.DocxTextElement{textRun(string(c.Tokens), style)}
+		}
+		return nil
+	case ast.NodeInlineHTML, ast.NodeEmojiUnicode, ast.NodeEmojiAlias:
+		return []*lark.DocxTextElement{textRun(string(n.Tokens), style)}
+	case ast.NodeTaskListItemMarker:
+		return nil
+	}
+	return convertMarkdownInlines(n, style)
+}
+
+// 获取链接或图片的地址
+func markdownLinkDest(n *ast.Node) string {
+	if dest := n.ChildByType(ast.NodeLinkDest); dest != nil {
+		return string(dest.Tokens)
+	}
+	return ""
+}
+
+func textRun(content string, style *lark.DocxTextElementStyle) *lark.DocxTextElement {
+	run := &lark.DocxTextElementTextRun{Content: content}
+	if *style != (lark.DocxTextElementStyle{}) {
+		s := *style
+		run.TextElementStyle = &s
+	}
+	return &lark.DocxTextElement{TextRun: run}
+}
+
+func textElements(content string) []*lark.DocxTextElement {
+	return []*lark.DocxTextElement{textRun(content, &lark.DocxTextElementStyle{})}
+}
+
+// 追加文本元素，样式相同的相邻文字合并为一个元素
+func appendTextElements(elements []*lark.DocxTextElement, items ...*lark.DocxTextElement) []*lark.DocxTextElement {
+	for _, item := range items {
+		if len(elements) > 0 {
+			last := elements[len(elements)-1]
+			if last.TextRun != nil && item.TextRun != nil && sameTextStyle(last.TextRun.TextElementStyle, item.TextRun.TextElementStyle) {
+				last.TextRun.Content += item.TextRun.Content
+				continue
+			}
+		}
+		elements = append(elements, item)
+	}
+	return elements
+}
+
+func sameTextStyle(a, b *lark.DocxTextElementStyle) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if (a.Link == nil) != (b.Link == nil) || (a.Link != nil && a.Link.URL != b.Link.URL) {
+		return false
+	}
+	x, y := *a, *b
+	x.Link, y.Link = nil, nil
+	return x == y
+}
+
+func hasVisibleText(elements []*lark.DocxTextElement) bool {
+	for _, e := range elements {
+		if e.TextRun == nil || strings.TrimSpace(e.TextRun.Content) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportMarkdown 将 Markdown 导入为新的文档，标题为空时使用第一个一级标题
+func (c *Client) ImportMarkdown(ctx context.Context, markdown string, opts ImportOptions) (*ImportResult, error) {
+	blocks := ParseMarkdownToBlocks(markdown)
+	title := opts.Title
+	if title == "" && len(blocks) > 0 && blocks[0].Block.BlockType == lark.DocxBlockTypeHeading1 {
+		title = plainText(blocks[0].Block.Heading1.Elements)
+		blocks = blocks[1:]
+	}
+	if title == "" {
+		title = "Untitled"
+	}
+
+	result := &ImportResult{Title: title}
+	if opts.WikiSpaceID != "" {
+		req := &lark.CreateWikiNodeReq{
+			SpaceID:  opts.WikiSpaceID,
+			ObjType:  "docx",
+			NodeType: "origin",
+		}
+		if opts.WikiParentNodeToken != "" {
+			req.ParentNodeToken = &opts.WikiParentNodeToken
+		}
+		resp, _, err := c.larkClient.Drive.CreateWikiNode(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("创建知识库节点失败: %w", err)
+		}
+		result.DocumentID = resp.Node.ObjToken
+		result.NodeToken = resp.Node.NodeToken
+		_, _, err = c.larkClient.Drive.UpdateWikiNodeTitle(ctx, &lark.UpdateWikiNodeTitleReq{
+			SpaceID:   opts.WikiSpaceID,
+			NodeToken: resp.Node.NodeToken,
+			Title:     title,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("设置知识库节点标题失败: %w", err)
+		}
+	} else {
+		req := &lark.CreateDocxReq{Title: &title}
+		if opts.FolderToken != "" {
+			req.FolderToken = &opts.FolderToken
+		}
+		resp, _, err := c.larkClient.Drive.CreateDocx(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("创建文档失败: %w", err)
+		}
+		result.DocumentID = resp.Document.DocumentID
+	}
+
+	if err := c.createImportBlocks(ctx, result.DocumentID, result.DocumentID, blocks, opts.BaseDir); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+func plainText(elements []*lark.DocxTextElement) string {
+	buf := new(strings.Builder)
+	for _, e := range elements {
+		if e.TextRun != nil {
+			buf.WriteString(e.TextRun.Content)
+		}
+		if e.Equation != nil {
+			buf.WriteString(e.Equation.Content)
+		}
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// 在 parentID 下依次创建文档块，再处理图片、表格单元格和子块
+func (c *Client) createImportBlocks(ctx context.Context, documentID, parentID string, blocks []*ImportBlock, baseDir string) error {
+	for start := 0; start < len(blocks); start += docxChildrenPerRequest {
+		end := start + docxChildrenPerRequest
+		if end > len(blocks) {
+			end = len(blocks)
+		}
+		batch := blocks[start:end]
+		children := make([]*lark.DocxBlock, len(batch))
+		for i, block := range batch {
+			children[i] = block.Block
+		}
+		created, err := c.CreateDocxChildren(ctx, documentID, parentID, children)
+		if err != nil {
+			return err
+		}
+		if len(created) != len(batch) {
+			return fmt.Errorf("创建文档块数量不一致: 期望 %d, 实际 %d", len(batch), len(created))
+		}
+
+		for i, block := range batch {
+			blockID := created[i].BlockID
+			if block.ImageSrc != "" {
+				// 图片上传失败不影响其他内容的导入
+				if err := c.uploadImportImage(ctx, documentID, blockID, block.ImageSrc, baseDir); err != nil {
+					log.Printf("上传图片 %s 失败: %s", block.ImageSrc, err)
+				}
+			}
+			if block.Cells != nil && created[i].Table != nil {
+				if err := c.fillImportTableCells(ctx, documentID, created[i].Table.Cells, block.Cells); err != nil {
+					return err
+				}
+			}
+			if len(block.Children) > 0 {
+				if err := c.createImportBlocks(ctx, documentID, blockID, block.Children, baseDir); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// CreateDocxChildren 在指定块下追加子块
+// SDK 中分割线字段不是指针，任何块都会带上 divider 字段，这里自行序列化请求体
+func (c *Client) CreateDocxChildren(ctx context.Context, documentID, blockID string, children []*lark.DocxBlock) ([]*lark.DocxBlock, error) {
+	items := make([]map[string]interface{}, 0, len(children))
+	for _, child := range children {
+		raw, err := json.Marshal(child)
+		if err != nil {
+			return nil, err
+		}
+		item := map[string]interface{}{}
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return nil, err
+		}
+		if child.BlockType != lark.DocxBlockTypeDivider {
+			delete(item, "divider")
+		}
+		items = append(items, item)
+	}
+
+	var resp struct {
+		Code int64  `json:"code"`
+		Msg  string `json:"msg"`
+		Data struct {
+			Children []*lark.DocxBlock `json:"children"`
+		} `json:"data"`
+	}
+	_, err := c.larkClient.RawRequest(ctx, &lark.RawRequestReq{
+		Scope:  "Drive",
+		API:    "CreateDocxBlock",
+		Method: "POST",
+		URL:    c.baseURL + "/open-apis/docx/v1/documents/:document_id/blocks/:block_id/children",
+		Body: &struct {
+			DocumentID         string                   `path:"document_id" json:"-"`
+			BlockID            string                   `path:"block_id" json:"-"`
+			DocumentRevisionID int64                    `query:"document_revision_id" json:"-"`
+			Children           []map[string]interface{} `json:"children"`
+		}{
+			DocumentID:         documentID,
+			BlockID:            blockID,
+			DocumentRevisionID: -1,
+			Children:           items,
+		},
+		MethodOption:          &lark.MethodOption{},
+		NeedTenantAccessToken: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data.Children, nil
+}
+
+// 新建的表格单元格中已有一个空文本块，直接更新它的内容
+func (c *Client) fillImportTableCells(ctx context.Context, documentID string, cellIDs []string, rows [][][]*lark.DocxTextElement) error {
+	index := 0
+	for _, row := range rows {
+		for _, elements := range row {
+			if index >= len(cellIDs) {
+				return nil
+			}
+			cellID := cellIDs[index]
+			index++
+			if !hasVisibleText(elements) {
+				continue
+			}
+			resp, _, err := c.larkClient.Drive.GetDocxBlockListOfBlock(ctx, &lark.GetDocxBlockListOfBlockReq{
+				DocumentID: documentID,
+				BlockID:    cellID,
+			})
+			if err != nil {
+				return err
+			}
+			if len(resp.Items) == 0 {
+				if _, err := c.CreateDocxChildren(ctx, documentID, cellID, []*lark.DocxBlock{{
+					BlockType: lark.DocxBlockTypeText,
+					Text:      &lark.DocxBlockText{Elements: elements},
+				}}); err != nil {
+					return err
+				}
+				continue
+			}
+			_, _, err = c.larkClient.Drive.UpdateDocxBlock(ctx, &lark.UpdateDocxBlockReq{
+				DocumentID:         documentID,
+				BlockID:            resp.Items[0].BlockID,
+				UpdateTextElements: &lark.UpdateDocxBlockReqUpdateTextElements{Elements: elements},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 上传图片到图片块，src 为相对 baseDir 的本地路径或 http 链接
+func (c *Client) uploadImportImage(ctx context.Context, documentID, blockID, src, baseDir string) error {
+	var data []byte
+	var err error
+	name := filepath.Base(src)
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		data, err = fetchImage(ctx, src)
+		if u, parseErr := url.Parse(src); parseErr == nil {
+			name = filepath.Base(u.Path)
+		}
+	} else {
+		var path string
+		if path, err = ResolveImportImage(baseDir, src); err == nil {
+			data, err = readImportImageFile(path)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	upload, _, err := c.larkClient.Drive.UploadDriveMedia(ctx, &lark.UploadDriveMediaReq{
+		FileName:   name,
+		ParentType: "docx_image",
+		ParentNode: blockID,
+		Size:       int64(len(data)),
+		File:       bytes.NewReader(data),
+	})
+	if err != nil {
+		return err
+	}
+	_, _, err = c.larkClient.Drive.UpdateDocxBlock(ctx, &lark.UpdateDocxBlockReq{
+		DocumentID:   documentID,
+		BlockID:      blockID,
+		ReplaceImage: &lark.UpdateDocxBlockReqReplaceImage{Token: upload.FileToken},
+	})
+	return err
+}
+
+// ResolveImportImage 返回 Markdown 中本地图片的路径，只允许 baseDir 中的相对路径，
+// 绝对路径、.. 和指向目录外的符号链接都会被拒绝，避免通过导入读取任意文件
+func ResolveImportImage(baseDir, src string) (string, error) {
+	if baseDir == "" {
+		return "", fmt.Errorf("没有指定图片目录，不能上传本地图片: %s", src)
+	}
+	rel, err := url.PathUnescape(src)
+	if err != nil {
+		rel = src
+	}
+	rel = filepath.FromSlash(rel)
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("图片路径不在图片目录中: %s", src)
+	}
+	root, err := filepath.EvalSymlinks(baseDir)
+	if err != nil {
+		return "", err
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(root, rel))
+	if err != nil {
+		return "", err
+	}
+	if inside, err := filepath.Rel(root, path); err != nil || !filepath.IsLocal(inside) {
+		return "", fmt.Errorf("图片路径不在图片目录中: %s", src)
+	}
+	return path, nil
+}
+
+// 下载网络图片的客户端，只连接公网地址，重定向到内网地址同样会被拒绝
+var publicHTTPClient = NewPublicHTTPClient(60 * time.Second)
+
+// NewPublicHTTPClient 创建只能连接公网地址的 HTTP 客户端，用于下载用户提供的链接，防止访问本机和内网服务
+func NewPublicHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("不允许访问非公网地址: %s", host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: 10 * time.Second},
+	}
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+func fetchImage(ctx context.Context, src string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := publicHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载图片失败，状态码: %d", resp.StatusCode)
+	}
+	return ReadImportImage(resp.Body)
+}
+
+func readImportImageFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadImportImage(f)
+}
+
+// ReadImportImage 读取待上传的图片，超过 MaxImportImageSize 时返回错误，避免把过大的响应整个读入内存
+func ReadImportImage(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxImportImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxImportImageSize {
+		return nil, fmt.Errorf("图片超过 %dMB 的上传限制", MaxImportImageSize>>20)
+	}
+	return data, nil
+}
@@ -0,0 +1,125 @@
+package core_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/chyroc/lark"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMarkdownToBlocks(t *testing.T) {
+	markdown := "# 标题\n\n" +
+		"普通 **加粗** 和 [链接](https://example.com/a?b=1) 以及 `code` $E=mc^2$\n\n" +
+		"- 一\n  - 二\n\n" +
+		"1. 第一\n\n" +
+		"- [x] 完成\n- [ ] 未完成\n\n" +
+		"> 引用\n\n" +
+		"```golang\nfmt.Println(1)\n```\n\n" +
+		"$$\na^2\n$$\n\n" +
+		"---\n\n" +
+		"| a | b |\n| - | - |\n| 1 | 2 |\n\n" +
+		"文字 ![图](./img/a.png)\n"
+
+	blocks := core.ParseMarkdownToBlocks(markdown)
+	types := make([]lark.DocxBlockType, 0, len(blocks))
+	for _, b := range blocks {
+		types = append(types, b.Block.BlockType)
+	}
+	assert.Equal(t, []lark.DocxBlockType{
+		lark.DocxBlockTypeHeading1,
+		lark.DocxBlockTypeText,
+		lark.DocxBlockTypeBullet,
+		lark.DocxBlockTypeOrdered,
+		lark.DocxBlockTypeTodo,
+		lark.DocxBlockTypeTodo,
+		lark.DocxBlockTypeQuote,
+		lark.DocxBlockTypeCode,
+		lark.DocxBlockTypeText,
+		lark.DocxBlockTypeDivider,
+		lark.DocxBlockTypeTable,
+		lark.DocxBlockTypeText,
+		lark.DocxBlockTypeImage,
+	}, types)
+
+	text := blocks[1].Block.Text.Elements
+	assert.Equal(t, "普通 ", text[0].TextRun.Content)
+	assert.Equal(t, "加粗", text[1].TextRun.Content)
+	assert.True(t, text[1].TextRun.TextElementStyle.Bold)
+	assert.Equal(t, "链接", text[3].TextRun.Content)
+	assert.NotNil(t, text[3].TextRun.TextElementStyle.Link)
+	assert.True(t, text[5].TextRun.TextElementStyle.InlineCode)
+	assert.Equal(t, "E=mc^2", text[7].Equation.Content)
+
+	assert.Len(t, blocks[2].Children, 1)
+	assert.Equal(t, lark.DocxBlockTypeBullet, blocks[2].Children[0].Block.BlockType)
+	assert.True(t, blocks[4].Block.Todo.Style.Done)
+	assert.False(t, blocks[5].Block.Todo.Style.Done)
+	assert.Equal(t, lark.DocxCodeLanguageGo, blocks[7].Block.Code.Style.Language)
+	assert.Equal(t, "fmt.Println(1)", blocks[7].Block.Code.Elements[0].TextRun.Content)
+	assert.Equal(t, "a^2", blocks[8].Block.Text.Elements[0].Equation.Content)
+
+	table := blocks[10]
+	assert.Equal(t, int64(2), table.Block.Table.Property.RowSize)
+	assert.Equal(t, int64(2), table.Block.Table.Property.ColumnSize)
+	assert.Equal(t, "2", table.Cells[1][1][0].TextRun.Content)
+
+	assert.Equal(t, "./img/a.png", blocks[12].ImageSrc)
+}
+
+func TestMdStr2DocxCodeLang(t *testing.T) {
+	for lang, str := range core.DocxCodeLang2MdStr {
+		assert.Equal(t, lang, core.MdStr2DocxCodeLang(str))
+	}
+	assert.Equal(t, lark.DocxCodeLanguageTypeScript, core.MdStr2DocxCodeLang("TS"))
+	assert.Equal(t, lark.DocxCodeLanguagePlainText, core.MdStr2DocxCodeLang("unknown"))
+}
+
+func TestResolveImportImage(t *testing.T) {
+	root := t.TempDir()
+	base := filepath.Join(root, "docs")
+	img := writeTestFile(t, filepath.Join(base, "img", "a.png"), "png")
+	secret := writeTestFile(t, filepath.Join(root, "secret.txt"), "secret")
+	assert.NoError(t, os.Symlink(secret, filepath.Join(base, "link.png")))
+
+	path, err := core.ResolveImportImage(base, "./img/a.png")
+	assert.NoError(t, err)
+	resolvedImg, _ := filepath.EvalSymlinks(img)
+	assert.Equal(t, resolvedImg, path)
+	_, err = core.ResolveImportImage(base, "img/%61.png")
+	assert.NoError(t, err)
+
+	for _, src := range []string{"../secret.txt", "img/../../secret.txt", secret, "link.png", "%2E%2E/secret.txt"} {
+		_, err := core.ResolveImportImage(base, src)
+		assert.Error(t, err, src)
+	}
+	_, err = core.ResolveImportImage("", "img/a.png")
+	assert.Error(t, err)
+}
+
+func TestPublicHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer server.Close()
+
+	// 本机地址的请求在建立连接前被拒绝
+	_, err := core.NewPublicHTTPClient(time.Second).Get(server.URL)
+	assert.ErrorContains(t, err, "不允许访问非公网地址")
+}
+
+func TestReadImportImage(t *testing.T) {
+	data, err := core.ReadImportImage(bytes.NewReader(make([]byte, core.MaxImportImageSize)))
+	assert.NoError(t, err)
+	assert.Len(t, data, core.MaxImportImageSize)
+
+	// 超过上传限制的图片直接报错
+	_, err = core.ReadImportImage(bytes.NewReader(make([]byte, core.MaxImportImageSize+1)))
+	assert.ErrorContains(t, err, "上传限制")
+}
@@ -0,0 +1,302 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/chyroc/lark"
+)
+
+// UserToken 通过 OAuth 授权得到的用户访问令牌
+type UserToken struct {
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	OpenID           string    `json:"open_id"`
+	Name             string    `json:"name"`
+}
+
+// UserTokenStore 保存用户令牌，Load 在没有令牌时返回 nil, nil
+type UserTokenStore interface {
+	Load() (*UserToken, error)
+	Save(token *UserToken) error
+	Delete() error
+}
+
+// FileTokenStore 将用户令牌保存在只有当前用户可读写的文件中
+// 设置了加密口令时，访问令牌和刷新令牌与配置中的密钥一样加密保存
+type FileTokenStore struct {
+	Path string
+}
+
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{Path: path}
+}
+
+// GetUserTokenFilePath 返回默认的用户令牌文件路径，与配置文件位于同一目录
+func GetUserTokenFilePath() (string, error) {
+	configPath, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(configPath, "feishu2md", "user_token.json"), nil
+}
+
+func (s *FileTokenStore) Load() (*UserToken, error) {
+	data, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	token := &UserToken{}
+	if err := json.Unmarshal(data, token); err != nil {
+		return nil, err
+	}
+	if IsEncryptedSecret(token.AccessToken) || IsEncryptedSecret(token.RefreshToken) {
+		passphrase, err := LookupPassphrase()
+		if err != nil {
+			return nil, err
+		}
+		if passphrase == "" {
+			return nil, ErrPassphraseRequired
+		}
+		if err := token.transformSecrets(NewSecretCipher(passphrase).Decrypt); err != nil {
+			return nil, err
+		}
+	}
+	return token, nil
+}
+
+func (s *FileTokenStore) Save(token *UserToken) error {
+	passphrase, err := LookupPassphrase()
+	if err != nil {
+		return err
+	}
+	saved := *token
+	if passphrase != "" {
+		if err := saved.transformSecrets(NewSecretCipher(passphrase).Encrypt); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(&saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.Path, data, 0o600); err != nil {
+		return err
+	}
+	// 文件已存在时 WriteFile 不会修改权限
+	return os.Chmod(s.Path, 0o600)
+}
+
+func (s *FileTokenStore) Delete() error {
+	err := os.Remove(s.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileTokenStore) String() string {
+	return s.Path
+}
+
+// 依次处理访问令牌和刷新令牌
+func (t *UserToken) transformSecrets(fn func(string) (string, error)) error {
+	var err error
+	if t.AccessToken, err = fn(t.AccessToken); err != nil {
+		return err
+	}
+	t.RefreshToken, err = fn(t.RefreshToken)
+	return err
+}
+
+// ErrUserTokenExpired 用户令牌和刷新令牌都已过期，需要重新登录
+var ErrUserTokenExpired = errors.New("用户登录已过期，请重新登录")
+
+// WithUserTokenStore 存在已登录的用户令牌时以用户身份调用接口，否则使用应用身份
+func WithUserTokenStore(store UserTokenStore) ClientOption {
+	return func(c *Client) {
+		c.userTokenStore = store
+	}
+}
+
+// AuthorizeURL 生成用户授权登录的链接，授权后会携带 code 和 state 跳转到 redirectURI
+func (c *Client) AuthorizeURL(ctx context.Context, redirectURI, state string) string {
+	return c.larkClient.Auth.GenOAuthURL(ctx, &lark.GenOAuthURLReq{
+		RedirectURI: redirectURI,
+		State:       state,
+	})
+}
+
+// ExchangeCode 使用授权码换取用户令牌并保存
+func (c *Client) ExchangeCode(ctx context.Context, code string) (*UserToken, error) {
+	resp, _, err := c.larkClient.Auth.GetAccessToken(ctx, &lark.GetAccessTokenReq{
+		GrantType: "authorization_code",
+		Code:      code,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取用户访问令牌失败: %w", err)
+	}
+	now := time.Now()
+	token := &UserToken{
+		AccessToken:      resp.AccessToken,
+		RefreshToken:     resp.RefreshToken,
+		ExpiresAt:        now.Add(time.Duration(resp.ExpiresIn) * time.Second),
+		RefreshExpiresAt: now.Add(time.Duration(resp.RefreshExpiresIn) * time.Second),
+		OpenID:           resp.OpenID,
+		Name:             resp.Name,
+	}
+	if err := c.saveUserToken(token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// UserAccessToken 返回有效的用户令牌，即将过期时自动刷新
+// 没有配置令牌存储或尚未登录时返回空字符串
+func (c *Client) UserAccessToken(ctx context.Context) (string, error) {
+	if c.userTokenStore == nil {
+		return "", nil
+	}
+	c.userMu.Lock()
+	defer c.userMu.Unlock()
+
+	if c.userToken == nil {
+		token, err := c.userTokenStore.Load()
+		if err != nil {
+			return "", fmt.Errorf("读取用户令牌失败: %w", err)
+		}
+		if token == nil {
+			return "", nil
+		}
+		c.userToken = token
+	}
+
+	now := time.Now()
+	if now.Before(c.userToken.ExpiresAt.Add(-tokenRefreshMargin)) {
+		return c.userToken.AccessToken, nil
+	}
+	if c.userToken.RefreshToken == "" || now.After(c.userToken.RefreshExpiresAt) {
+		// 刷新令牌过期后无法恢复，删除保存的令牌，之后的请求使用应用身份
+		c.userToken = nil
+		if err := c.userTokenStore.Delete(); err != nil {
+			log.Printf("删除过期的用户令牌失败: %s", err)
+		}
+		return "", ErrUserTokenExpired
+	}
+
+	resp, _, err := c.larkClient.Auth.RefreshAccessToken(ctx, &lark.RefreshAccessTokenReq{
+		GrantType:    "refresh_token",
+		RefreshToken: c.userToken.RefreshToken,
+	})
+	if err != nil {
+		return "", fmt.Errorf("刷新用户访问令牌失败: %w", err)
+	}
+	token := &UserToken{
+		AccessToken:      resp.AccessToken,
+		RefreshToken:     resp.RefreshToken,
+		ExpiresAt:        now.Add(time.Duration(resp.ExpiresIn) * time.Second),
+		RefreshExpiresAt: now.Add(time.Duration(resp.RefreshExpiresIn) * time.Second),
+		OpenID:           resp.OpenID,
+		Name:             resp.Name,
+	}
+	if err := c.userTokenStore.Save(token); err != nil {
+		return "", fmt.Errorf("保存用户令牌失败: %w", err)
+	}
+	c.userToken = token
+	return token.AccessToken, nil
+}
+
+// CurrentUser 返回已登录的用户，未登录时返回 nil
+func (c *Client) CurrentUser() (*UserToken, error) {
+	if c.userTokenStore == nil {
+		return nil, nil
+	}
+	c.userMu.Lock()
+	defer c.userMu.Unlock()
+	if c.userToken != nil {
+		return c.userToken, nil
+	}
+	return c.userTokenStore.Load()
+}
+
+// Logout 删除保存的用户令牌，之后使用应用身份调用接口
+func (c *Client) Logout() error {
+	if c.userTokenStore == nil {
+		return nil
+	}
+	c.userMu.Lock()
+	defer c.userMu.Unlock()
+	c.userToken = nil
+	return c.userTokenStore.Delete()
+}
+
+func (c *Client) saveUserToken(token *UserToken) error {
+	if c.userTokenStore == nil {
+		return errors.New("未配置用户令牌存储")
+	}
+	c.userMu.Lock()
+	defer c.userMu.Unlock()
+	if err := c.userTokenStore.Save(token); err != nil {
+		return fmt.Errorf("保存用户令牌失败: %w", err)
+	}
+	c.userToken = token
+	return nil
+}
+
+// 已登录时让 SDK 请求携带用户令牌，认证相关的接口仍使用应用身份
+func (c *Client) userTokenMiddleware(next lark.ApiEndpoint) lark.ApiEndpoint {
+	return func(ctx context.Context, req *lark.RawRequestReq, resp interface{}) (*lark.Response, error) {
+		if req.Scope == "Auth" || c.userTokenStore == nil {
+			return next(ctx, req, resp)
+		}
+		token, err := c.requestUserToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if token != "" {
+			if req.MethodOption == nil {
+				req.MethodOption = &lark.MethodOption{}
+			}
+			lark.WithUserAccessToken(token)(req.MethodOption)
+			req.NeedUserAccessToken = true
+		}
+		return next(ctx, req, resp)
+	}
+}
+
+// 直接发送 HTTP 请求时使用的令牌，已登录时优先使用用户令牌
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	token, err := c.requestUserToken(ctx)
+	if err != nil {
+		return "", wrapAPIError(err, nil)
+	}
+	if token != "" {
+		return token, nil
+	}
+	return c.GetTenantAccessToken(ctx)
+}
+
+// 调用接口时使用的用户令牌，登录过期时返回空字符串改用应用身份，不让之后的请求一直失败
+func (c *Client) requestUserToken(ctx context.Context) (string, error) {
+	token, err := c.UserAccessToken(ctx)
+	if errors.Is(err, ErrUserTokenExpired) {
+		log.Printf("%s，改用应用身份调用接口", err)
+		return "", nil
+	}
+	return token, err
+}
@@ -0,0 +1,136 @@
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileTokenStore(t *testing.T) {
+	store := core.NewFileTokenStore(filepath.Join(t.TempDir(), "feishu2md", "user_token.json"))
+	token, err := store.Load()
+	assert.NoError(t, err)
+	assert.Nil(t, token)
+
+	assert.NoError(t, store.Save(&core.UserToken{AccessToken: "u-1", Name: "alice"}))
+	info, err := os.Stat(store.Path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	token, err = store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "u-1", token.AccessToken)
+
+	assert.NoError(t, store.Delete())
+	token, err = store.Load()
+	assert.NoError(t, err)
+	assert.Nil(t, token)
+}
+
+func TestFileTokenStoreEncrypted(t *testing.T) {
+	t.Setenv(core.PassphraseEnv, "passphrase")
+	store := core.NewFileTokenStore(filepath.Join(t.TempDir(), "user_token.json"))
+	assert.NoError(t, store.Save(&core.UserToken{AccessToken: "u-1", RefreshToken: "ur-1", Name: "alice"}))
+
+	// 文件中不保存明文令牌
+	data, err := os.ReadFile(store.Path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "u-1")
+	assert.NotContains(t, string(data), "ur-1")
+	assert.Contains(t, string(data), "alice")
+
+	token, err := store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "u-1", token.AccessToken)
+	assert.Equal(t, "ur-1", token.RefreshToken)
+
+	t.Setenv(core.PassphraseEnv, "")
+	_, err = store.Load()
+	assert.ErrorIs(t, err, core.ErrPassphraseRequired)
+}
+
+func TestUserAccessTokenRefresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/open-apis/auth/v3/app_access_token/internal":
+			fmt.Fprint(w, `{"code":0,"msg":"ok","app_access_token":"a-1","expire":7200}`)
+		case "/open-apis/authen/v1/refresh_access_token":
+			fmt.Fprint(w, `{"code":0,"msg":"ok","data":{"access_token":"u-2","refresh_token":"ur-2","expires_in":7200,"refresh_expires_in":86400,"name":"alice"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	store := core.NewFileTokenStore(filepath.Join(t.TempDir(), "user_token.json"))
+	assert.NoError(t, store.Save(&core.UserToken{
+		AccessToken:      "u-1",
+		RefreshToken:     "ur-1",
+		ExpiresAt:        time.Now().Add(time.Minute),
+		RefreshExpiresAt: time.Now().Add(time.Hour),
+	}))
+
+	client := core.NewClient("id", "secret", core.WithBaseURL(server.URL), core.WithUserTokenStore(store))
+	token, err := client.UserAccessToken(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "u-2", token)
+
+	saved, err := store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "ur-2", saved.RefreshToken)
+}
+
+func TestUserAccessTokenExpired(t *testing.T) {
+	store := core.NewFileTokenStore(filepath.Join(t.TempDir(), "user_token.json"))
+	assert.NoError(t, store.Save(&core.UserToken{
+		AccessToken:      "u-1",
+		RefreshToken:     "ur-1",
+		ExpiresAt:        time.Now().Add(-time.Hour),
+		RefreshExpiresAt: time.Now().Add(-time.Minute),
+	}))
+	client := core.NewClient("id", "secret", core.WithUserTokenStore(store))
+	_, err := client.UserAccessToken(context.Background())
+	assert.ErrorIs(t, err, core.ErrUserTokenExpired)
+
+	// 过期的令牌被删除，之后使用应用身份
+	saved, err := store.Load()
+	assert.NoError(t, err)
+	assert.Nil(t, saved)
+	token, err := client.UserAccessToken(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, token)
+}
+
+func TestUserTokenExpiredFallsBackToTenant(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/open-apis/auth/v3/tenant_access_token/internal":
+			fmt.Fprint(w, `{"code":0,"msg":"ok","tenant_access_token":"t-1","expire":7200}`)
+		default:
+			auth = r.Header.Get("Authorization")
+			fmt.Fprint(w, `{"code":0,"msg":"ok","data":{"items":[],"has_more":false}}`)
+		}
+	}))
+	defer server.Close()
+
+	store := core.NewFileTokenStore(filepath.Join(t.TempDir(), "user_token.json"))
+	assert.NoError(t, store.Save(&core.UserToken{
+		AccessToken:      "u-1",
+		RefreshToken:     "ur-1",
+		ExpiresAt:        time.Now().Add(-time.Hour),
+		RefreshExpiresAt: time.Now().Add(-time.Minute),
+	}))
+	client := core.NewClient("fallback-test", "secret", core.WithBaseURL(server.URL), core.WithUserTokenStore(store))
+	_, err := client.GetAllWikiSpaces(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer t-1", auth)
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/Wsine/feishu2md/utils"
+)
+
+// Profile 命名的应用凭证，用于从多个飞书或 Lark 租户导出文档
+type Profile struct {
+	Feishu FeishuConfig `json:"feishu"`
+	// Output 该租户使用的导出选项，配置文件中未设置的字段沿用全局的导出选项
+	Output *OutputConfig `json:"output,omitempty"`
+}
+
+// UnmarshalJSON 读取配置，命名配置中的导出选项以全局的导出选项为默认值
+func (conf *Config) UnmarshalJSON(data []byte) error {
+	type plainConfig Config
+	if err := json.Unmarshal(data, (*plainConfig)(conf)); err != nil {
+		return err
+	}
+	var raw struct {
+		Profiles map[string]struct {
+			Output json.RawMessage `json:"output"`
+		} `json:"profiles"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for name, rawProfile := range raw.Profiles {
+		if len(rawProfile.Output) == 0 || string(rawProfile.Output) == "null" {
+			continue
+		}
+		output := conf.Output
+		if err := json.Unmarshal(rawProfile.Output, &output); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+		profile := conf.Profiles[name]
+		profile.Output = &output
+		conf.Profiles[name] = profile
+	}
+	return nil
+}
+
+// ProfileNames 返回按名称排序的命名配置
+func (conf *Config) ProfileNames() []string {
+	names := make([]string, 0, len(conf.Profiles))
+	for name := range conf.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UseProfile 返回使用命名配置中的凭证和导出选项的配置副本，name 为空时返回全局配置的副本
+// 命名配置未设置接口地址时根据域名推断，未设置导出选项时使用全局的导出选项
+func (conf *Config) UseProfile(name string) (*Config, error) {
+	config := *conf
+	if name == "" {
+		return &config, nil
+	}
+	profile, ok := conf.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("配置 %s 不存在", name)
+	}
+	config.Feishu = profile.Feishu
+	if config.Feishu.Domain == "" {
+		config.Feishu.Domain = DefaultDomain
+	}
+	if config.Feishu.BaseURL == "" {
+		_, config.Feishu.BaseURL = utils.ParseDomain("https://" + config.Feishu.Domain)
+	}
+	if config.Feishu.BaseURL == "" {
+		config.Feishu.BaseURL = DefaultBaseURL
+	}
+	if profile.Output != nil {
+		config.Output = *profile.Output
+	}
+	config.ProfileName = name
+	return &config, nil
+}
+
+// MatchProfile 返回域名与文档链接最匹配的命名配置，全局配置匹配得更好或都不匹配时返回空字符串
+// 域名可以是租户的完整域名，例如 example.feishu.cn，用于区分使用相同顶级域名的租户
+func (conf *Config) MatchProfile(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	best, bestLen := "", matchDomain(host, conf.Feishu.Domain)
+	for _, name := range conf.ProfileNames() {
+		if n := matchDomain(host, conf.Profiles[name].Feishu.Domain); n > bestLen {
+			best, bestLen = name, n
+		}
+	}
+	return best
+}
+
+// SelectProfile 使用指定的命名配置，name 为空时根据文档链接选择
+func (conf *Config) SelectProfile(name, rawURL string) (*Config, error) {
+	if name == "" {
+		name = conf.MatchProfile(rawURL)
+	}
+	return conf.UseProfile(name)
+}
+
+// 域名匹配时返回域名的长度，不匹配时返回 0
+func matchDomain(host, domain string) int {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if domain == "" {
+		return 0
+	}
+	if host == domain || strings.HasSuffix(host, "."+domain) {
+		return len(domain)
+	}
+	return 0
+}
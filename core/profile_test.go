@@ -0,0 +1,81 @@
+package core_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+)
+
+const profileConfig = `{
+  "feishu": {"app_id": "default", "app_secret": "s", "domain": "feishu.cn"},
+  "output": {"output_path": "out", "sheet_format": "csv"},
+  "profiles": {
+    "team": {
+      "feishu": {"app_id": "team", "app_secret": "s", "domain": "team.feishu.cn"},
+      "output": {"output_path": "team"}
+    },
+    "lark": {
+      "feishu": {"app_id": "lark", "app_secret": "s", "domain": "larksuite.com"}
+    }
+  }
+}`
+
+func readProfileConfig(t *testing.T) *core.Config {
+	path := writeTestFile(t, filepath.Join(t.TempDir(), "config.json"), profileConfig)
+	config, err := core.ReadConfigFromFile(path)
+	assert.NoError(t, err)
+	return config
+}
+
+func TestProfileOutputInheritsGlobal(t *testing.T) {
+	config := readProfileConfig(t)
+	assert.Equal(t, []string{"lark", "team"}, config.ProfileNames())
+
+	team, err := config.UseProfile("team")
+	assert.NoError(t, err)
+	assert.Equal(t, "team", team.ProfileName)
+	assert.Equal(t, "team", team.Output.OutputPath)
+	assert.Equal(t, core.SheetFormatCSV, team.Output.SheetFormat)
+	assert.Equal(t, "static", team.Output.ImageDir)
+	assert.Equal(t, core.DefaultBaseURL, team.Feishu.BaseURL)
+
+	lark, err := config.UseProfile("lark")
+	assert.NoError(t, err)
+	assert.Equal(t, core.LarkBaseURL, lark.Feishu.BaseURL)
+	assert.Equal(t, "out", lark.Output.OutputPath)
+
+	_, err = config.UseProfile("missing")
+	assert.Error(t, err)
+}
+
+func TestMatchProfile(t *testing.T) {
+	config := readProfileConfig(t)
+	tests := map[string]string{
+		"https://team.feishu.cn/docx/abc":          "team",
+		"https://other.feishu.cn/docx/abc":         "",
+		"https://sample.sg.larksuite.com/wiki/abc": "lark",
+		"https://docs.example.com/docx/abc":        "",
+		"not a url":                                "",
+	}
+	for rawURL, want := range tests {
+		assert.Equal(t, want, config.MatchProfile(rawURL), rawURL)
+	}
+
+	selected, err := config.SelectProfile("", "https://team.feishu.cn/docx/abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "team", selected.Feishu.AppId)
+	selected, err = config.SelectProfile("lark", "https://team.feishu.cn/docx/abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "lark", selected.Feishu.AppId)
+}
+
+func TestProfileRoundTrip(t *testing.T) {
+	config := readProfileConfig(t)
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, config.WriteConfig2File(path))
+	loaded, err := core.ReadConfigFromFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, config, loaded)
+}
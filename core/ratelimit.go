@@ -0,0 +1,173 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chyroc/lark"
+	"golang.org/x/time/rate"
+)
+
+// RateLimit 一类接口的调用频率上限
+type RateLimit struct {
+	PerSecond float64 // 每秒请求数
+	Burst     int     // 允许的突发请求数
+}
+
+// DefaultRateLimits 按照接口路径前缀设置的频率上限，参考开放平台文档中各接口的频控策略
+// 空前缀对应未列出的接口
+var DefaultRateLimits = map[string]RateLimit{
+	"":                            {PerSecond: 4, Burst: 4},
+	"/open-apis/auth/":            {PerSecond: 50, Burst: 10},
+	"/open-apis/docx/":            {PerSecond: 5, Burst: 5},
+	"/open-apis/drive/v1/medias/": {PerSecond: 5, Burst: 5},
+	"/open-apis/wiki/":            {PerSecond: 100.0 / 60, Burst: 10}, // 100 次/分钟
+	"/open-apis/sheets/":          {PerSecond: 20, Burst: 20},
+	"/open-apis/bitable/":         {PerSecond: 20, Burst: 20},
+}
+
+// 收到限流响应后速率减半，最低降到上限的 1/16，之后每隔一段时间逐步恢复
+const (
+	minRateFactor       = 1.0 / 16
+	rateRecoverInterval = 5 * time.Second
+)
+
+// RateLimiter 按接口分桶的限流器，同一进程内同一应用的客户端默认共享同一个限流器
+type RateLimiter struct {
+	mu      sync.Mutex
+	limits  map[string]RateLimit
+	buckets map[string]*rateBucket
+}
+
+type rateBucket struct {
+	limit       RateLimit
+	limiter     *rate.Limiter
+	pausedUntil time.Time // 飞书要求等待的截止时间
+	lastChange  time.Time // 上次调整速率的时间
+}
+
+func NewRateLimiter(limits map[string]RateLimit) *RateLimiter {
+	l := &RateLimiter{limits: map[string]RateLimit{}, buckets: map[string]*rateBucket{}}
+	for prefix, limit := range limits {
+		l.limits[prefix] = limit
+	}
+	if _, ok := l.limits[""]; !ok {
+		l.limits[""] = DefaultRateLimits[""]
+	}
+	return l
+}
+
+// WithRateLimiter 为客户端使用单独的限流器
+func WithRateLimiter(limiter *RateLimiter) ClientOption {
+	return func(c *Client) {
+		if limiter != nil {
+			c.rateLimiter = limiter
+		}
+	}
+}
+
+// SetLimit 修改路径前缀对应的频率上限，已经降速的桶会重新开始计算
+func (l *RateLimiter) SetLimit(prefix string, limit RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits[prefix] = limit
+	delete(l.buckets, prefix)
+}
+
+// Limit 返回路径当前生效的每秒请求数，降速后会小于配置的上限
+func (l *RateLimiter) Limit(path string) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return float64(l.bucket(path).limiter.Limit())
+}
+
+// 返回路径匹配的最长前缀对应的桶，调用时需要持有锁
+func (l *RateLimiter) bucket(path string) *rateBucket {
+	prefix := ""
+	for p := range l.limits {
+		if strings.HasPrefix(path, p) && len(p) > len(prefix) {
+			prefix = p
+		}
+	}
+	b, ok := l.buckets[prefix]
+	if !ok {
+		limit := l.limits[prefix]
+		b = &rateBucket{limit: limit, limiter: rate.NewLimiter(rate.Limit(limit.PerSecond), limit.Burst)}
+		l.buckets[prefix] = b
+	}
+	return b
+}
+
+// Wait 等待直到可以发送请求
+func (l *RateLimiter) Wait(ctx context.Context, path string) error {
+	l.mu.Lock()
+	b := l.bucket(path)
+	pause := time.Until(b.pausedUntil)
+	l.mu.Unlock()
+
+	if pause > 0 {
+		timer := time.NewTimer(pause)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return b.limiter.Wait(ctx)
+}
+
+// Observe 根据请求结果调整速率：被限流时降速并暂停，正常响应时逐步恢复
+func (l *RateLimiter) Observe(path string, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.bucket(path)
+	now := time.Now()
+	current := float64(b.limiter.Limit())
+
+	if errors.Is(err, ErrRateLimited) {
+		if retryAfter := RetryAfter(err); retryAfter > 0 && now.Add(retryAfter).After(b.pausedUntil) {
+			b.pausedUntil = now.Add(retryAfter)
+		}
+		slowed := max(current/2, b.limit.PerSecond*minRateFactor)
+		if slowed < current {
+			log.Printf("接口 %s 触发限流，速率降低到每秒 %.2f 次", path, slowed)
+			b.limiter.SetLimitAt(now, rate.Limit(slowed))
+		}
+		b.lastChange = now
+		return
+	}
+
+	if err == nil && current < b.limit.PerSecond && now.Sub(b.lastChange) >= rateRecoverInterval {
+		b.limiter.SetLimitAt(now, rate.Limit(min(current*2, b.limit.PerSecond)))
+		b.lastChange = now
+	}
+}
+
+// 请求地址中的路径，用于匹配限流的桶
+func requestPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Path
+}
+
+// 为 SDK 请求应用限流，并根据结果调整速率
+func (c *Client) rateLimitMiddleware(next lark.ApiEndpoint) lark.ApiEndpoint {
+	return func(ctx context.Context, req *lark.RawRequestReq, resp interface{}) (*lark.Response, error) {
+		path := requestPath(req.URL)
+		if err := c.rateLimiter.Wait(ctx, path); err != nil {
+			return nil, wrapAPIError(err, nil)
+		}
+		response, err := next(ctx, req, resp)
+		err = wrapAPIError(err, response)
+		c.rateLimiter.Observe(path, err)
+		return response, err
+	}
+}
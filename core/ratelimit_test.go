@@ -0,0 +1,72 @@
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterBuckets(t *testing.T) {
+	limiter := core.NewRateLimiter(map[string]core.RateLimit{
+		"":                 {PerSecond: 4, Burst: 4},
+		"/open-apis/":      {PerSecond: 10, Burst: 10},
+		"/open-apis/wiki/": {PerSecond: 2, Burst: 2},
+	})
+	assert.Equal(t, 2.0, limiter.Limit("/open-apis/wiki/v2/spaces"))
+	assert.Equal(t, 10.0, limiter.Limit("/open-apis/docx/v1/documents/doc"))
+	assert.Equal(t, 4.0, limiter.Limit("/other"))
+}
+
+func TestRateLimiterSlowDown(t *testing.T) {
+	limiter := core.NewRateLimiter(map[string]core.RateLimit{
+		"/open-apis/docx/": {PerSecond: 8, Burst: 8},
+	})
+	path := "/open-apis/docx/v1/documents/doc"
+	limited := &core.APIError{Kind: core.ErrRateLimited, RetryAfter: 100 * time.Millisecond}
+
+	limiter.Observe(path, limited)
+	assert.Equal(t, 4.0, limiter.Limit(path))
+	// 其他接口不受影响
+	assert.Equal(t, 4.0, limiter.Limit("/open-apis/wiki/v2/spaces"))
+
+	// 飞书要求等待的时间内暂停发送请求
+	start := time.Now()
+	assert.NoError(t, limiter.Wait(context.Background(), path))
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+
+	// 最低降到上限的 1/16
+	for i := 0; i < 10; i++ {
+		limiter.Observe(path, &core.APIError{Kind: core.ErrRateLimited})
+	}
+	assert.Equal(t, 0.5, limiter.Limit(path))
+}
+
+func TestRateLimiterSharedByClients(t *testing.T) {
+	var limited int32
+	server := newErrorServer(func(w http.ResponseWriter) {
+		atomic.AddInt32(&limited, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"code":99991400,"msg":"request trigger frequency limit"}`)
+	})
+	defer server.Close()
+
+	limiter := core.NewRateLimiter(core.DefaultRateLimits)
+	noRetry := core.WithRetryPolicy(core.RetryPolicy{})
+	a := core.NewClient("a", "secret", core.WithBaseURL(server.URL), core.WithRateLimiter(limiter), noRetry)
+	b := core.NewClient("b", "secret", core.WithBaseURL(server.URL), core.WithRateLimiter(limiter), noRetry)
+
+	_, err := a.GetWikiNodeInfo(context.Background(), "wiki")
+	assert.ErrorIs(t, err, core.ErrRateLimited)
+	// 直接发送 HTTP 请求的接口也使用同一个桶
+	_, err = b.GetAllWikiSpaces(context.Background())
+	assert.ErrorIs(t, err, core.ErrRateLimited)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&limited))
+	assert.Equal(t, core.DefaultRateLimits["/open-apis/wiki/"].PerSecond/4, limiter.Limit("/open-apis/wiki/v2/spaces"))
+}
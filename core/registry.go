@@ -0,0 +1,69 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ClientRegistry 按照凭证和站点缓存客户端，使同一应用共享访问令牌和限流器
+// 飞书的频控按应用计算，限流器按照应用 ID 区分，不同应用的请求互不影响
+type ClientRegistry struct {
+	mu       sync.Mutex
+	clients  map[string]*Client
+	limiters map[string]*RateLimiter // 应用 ID → 限流器
+}
+
+func NewClientRegistry() *ClientRegistry {
+	return &ClientRegistry{clients: map[string]*Client{}, limiters: map[string]*RateLimiter{}}
+}
+
+// RateLimiter 返回应用使用的限流器，不存在时按照默认的频率上限创建
+func (r *ClientRegistry) RateLimiter(appID string) *RateLimiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rateLimiterLocked(appID)
+}
+
+func (r *ClientRegistry) rateLimiterLocked(appID string) *RateLimiter {
+	limiter, ok := r.limiters[appID]
+	if !ok {
+		limiter = NewRateLimiter(DefaultRateLimits)
+		r.limiters[appID] = limiter
+	}
+	return limiter
+}
+
+// Get 返回已有的客户端，不存在时按照参数创建
+func (r *ClientRegistry) Get(appID, appSecret string, options ...ClientOption) *Client {
+	// 先应用选项得到最终的接口地址、域名等配置，作为缓存键的一部分
+	probe := &Client{baseURL: DefaultBaseURL, domain: DefaultDomain, retryPolicy: DefaultRetryPolicy}
+	for _, option := range options {
+		option(probe)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if probe.rateLimiter == nil {
+		probe.rateLimiter = r.rateLimiterLocked(appID)
+	}
+	key := strings.Join([]string{appID, appSecret, probe.baseURL, probe.domain, fmt.Sprint(probe.userTokenStore), fmt.Sprint(probe.retryPolicy), fmt.Sprintf("%p", probe.rateLimiter)}, "\n")
+	if client, ok := r.clients[key]; ok {
+		return client
+	}
+	client := NewClient(appID, appSecret, append(options, WithRateLimiter(probe.rateLimiter))...)
+	r.clients[key] = client
+	return client
+}
+
+var defaultRegistry = NewClientRegistry()
+
+// AppRateLimiter 返回进程级注册表中应用使用的限流器，未指定限流器的客户端都使用它
+func AppRateLimiter(appID string) *RateLimiter {
+	return defaultRegistry.RateLimiter(appID)
+}
+
+// GetClient 从进程级的客户端注册表中获取客户端
+func GetClient(appID, appSecret string, options ...ClientOption) *Client {
+	return defaultRegistry.Get(appID, appSecret, options...)
+}
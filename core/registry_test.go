@@ -0,0 +1,137 @@
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientRegistry(t *testing.T) {
+	registry := core.NewClientRegistry()
+	a := registry.Get("id", "secret")
+	b := registry.Get("id", "secret", core.WithDomain(core.DefaultDomain))
+	c := registry.Get("id", "secret", core.WithBaseURL(core.LarkBaseURL))
+	d := registry.Get("id", "other")
+	assert.Same(t, a, b)
+	assert.NotSame(t, a, c)
+	assert.NotSame(t, a, d)
+}
+
+func TestClientRegistryRateLimiter(t *testing.T) {
+	registry := core.NewClientRegistry()
+	limiter := registry.RateLimiter("a")
+	assert.Same(t, limiter, registry.RateLimiter("a"))
+	assert.NotSame(t, limiter, registry.RateLimiter("b"))
+
+	server := newErrorServer(func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"code":99991400,"msg":"request trigger frequency limit"}`)
+	})
+	defer server.Close()
+
+	// 同一应用的客户端共享限流器，不同应用的限流互不影响
+	noRetry := core.WithRetryPolicy(core.RetryPolicy{})
+	_, err := registry.Get("a", "secret", core.WithBaseURL(server.URL), noRetry).GetAllWikiSpaces(context.Background())
+	assert.ErrorIs(t, err, core.ErrRateLimited)
+	_, err = registry.Get("a", "other", core.WithBaseURL(server.URL), noRetry).GetWikiNodeInfo(context.Background(), "wiki")
+	assert.ErrorIs(t, err, core.ErrRateLimited)
+	path := "/open-apis/wiki/v2/spaces"
+	assert.Equal(t, core.DefaultRateLimits["/open-apis/wiki/"].PerSecond/4, limiter.Limit(path))
+	assert.Equal(t, core.DefaultRateLimits["/open-apis/wiki/"].PerSecond, registry.RateLimiter("b").Limit(path))
+
+	// 显式指定的限流器优先
+	custom := core.NewRateLimiter(core.DefaultRateLimits)
+	assert.NotSame(t, registry.Get("a", "secret"), registry.Get("a", "secret", core.WithRateLimiter(custom)))
+}
+
+func TestTenantAccessTokenCache(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		fmt.Fprintf(w, `{"code":0,"msg":"ok","tenant_access_token":"t-%d","expire":7200}`, n)
+	}))
+	defer server.Close()
+
+	client := core.NewClient("id", "secret", core.WithBaseURL(server.URL))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := client.GetTenantAccessToken(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "t-1", token)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestTenantAccessTokenRefresh(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		// 有效期短于提前刷新的时间，每次都需要重新获取
+		fmt.Fprintf(w, `{"code":0,"msg":"ok","tenant_access_token":"t-%d","expire":60}`, n)
+	}))
+	defer server.Close()
+
+	client := core.NewClient("id", "secret", core.WithBaseURL(server.URL))
+	first, err := client.GetTenantAccessToken(context.Background())
+	assert.NoError(t, err)
+	second, err := client.GetTenantAccessToken(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "t-1", first)
+	assert.Equal(t, "t-2", second)
+}
+
+func TestTenantAccessTokenInvalidated(t *testing.T) {
+	var tokens int32
+	rejected := map[string]bool{}
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/tenant_access_token/internal") {
+			n := atomic.AddInt32(&tokens, 1)
+			fmt.Fprintf(w, `{"code":0,"msg":"ok","tenant_access_token":"t-%d","expire":7200}`, n)
+			return
+		}
+		// 每个接口第一次请求时令牌被吊销，之后接受新令牌
+		mu.Lock()
+		defer mu.Unlock()
+		if !rejected[r.URL.Path] {
+			rejected[r.URL.Path] = true
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":99991663,"msg":"tenant access token invalid"}`)
+			return
+		}
+		fmt.Fprint(w, `{"code":0,"msg":"ok","data":{"items":[],"files":[],"has_more":false}}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	ctx := context.Background()
+
+	// 直接发送的请求
+	_, _, err := client.GetWikiNodeListWithPagination(ctx, "space", nil)
+	assert.ErrorIs(t, err, core.ErrInvalidCredentials)
+	_, _, err = client.GetWikiNodeListWithPagination(ctx, "space", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&tokens))
+
+	// SDK 发送的请求
+	_, err = client.ListDriveFolder(ctx, "folder")
+	assert.ErrorIs(t, err, core.ErrInvalidCredentials)
+	_, err = client.ListDriveFolder(ctx, "folder")
+	assert.NoError(t, err)
+	token, err := client.GetTenantAccessToken(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "t-5", token)
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/chyroc/lark"
+)
+
+// RetryPolicy 接口调用失败时的重试策略，只有限流和临时错误会重试
+type RetryPolicy struct {
+	MaxRetries int           // 失败后最多重试的次数，0 表示不重试
+	BaseDelay  time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	MaxDelay   time.Duration // 单次等待时间的上限，飞书要求的等待时间不受此限制
+}
+
+// DefaultRetryPolicy 默认重试 3 次，等待约 1s、2s、4s
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  time.Second,
+	MaxDelay:   30 * time.Second,
+}
+
+// WithRetryPolicy 设置接口调用的重试策略
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// 第 attempt 次重试前的等待时间：指数退避加随机抖动，避免并发请求同时重试
+func (p RetryPolicy) delay(attempt int, err error) time.Duration {
+	d := p.BaseDelay << uint(attempt)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if d > 0 {
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+	if retryAfter := RetryAfter(err); retryAfter > d {
+		d = retryAfter
+	}
+	return d
+}
+
+// 非幂等的请求只在被限流时重试，其他错误下请求可能已经生效
+func shouldRetry(err error, idempotent bool) bool {
+	if idempotent {
+		return IsRetryable(err)
+	}
+	return errors.Is(err, ErrRateLimited)
+}
+
+// 按照重试策略执行 fn，直到成功、遇到不可重试的错误或用完重试次数
+func (c *Client) retry(ctx context.Context, idempotent bool, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= c.retryPolicy.MaxRetries || !shouldRetry(err, idempotent) || ctx.Err() != nil {
+			return err
+		}
+		delay := c.retryPolicy.delay(attempt, err)
+		log.Printf("请求失败，%v 后重试 (%d/%d): %s", delay, attempt+1, c.retryPolicy.MaxRetries, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// 为 SDK 请求应用重试策略，需要放在 errorMiddleware 之前以便根据错误分类判断
+func (c *Client) retryMiddleware(next lark.ApiEndpoint) lark.ApiEndpoint {
+	return func(ctx context.Context, req *lark.RawRequestReq, resp interface{}) (*lark.Response, error) {
+		// 上传的文件内容只能读取一次，无法重试
+		if req.IsFile {
+			return next(ctx, req, resp)
+		}
+		var response *lark.Response
+		err := c.retry(ctx, req.Method == http.MethodGet, func() error {
+			var err error
+			response, err = next(ctx, req, resp)
+			return err
+		})
+		return response, err
+	}
+}
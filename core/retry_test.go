@@ -0,0 +1,129 @@
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+)
+
+var fastRetry = core.WithRetryPolicy(core.RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  time.Millisecond,
+	MaxDelay:   5 * time.Millisecond,
+})
+
+func TestRetryRateLimited(t *testing.T) {
+	var requests int32
+	server := newErrorServer(func(w http.ResponseWriter) {
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"code":99991400,"msg":"request trigger frequency limit"}`)
+			return
+		}
+		fmt.Fprint(w, `{"code":0,"msg":"ok","data":{"node":{"space_id":"s1","title":"首页"}}}`)
+	})
+	defer server.Close()
+
+	client := newTestClient(server.URL, fastRetry)
+	node, err := client.GetWikiNodeInfo(context.Background(), "wiki")
+	assert.NoError(t, err)
+	assert.Equal(t, "首页", node.Title)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	var requests int32
+	server := newErrorServer(func(w http.ResponseWriter) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer server.Close()
+
+	client := newTestClient(server.URL, fastRetry)
+	_, err := client.GetWikiNodeInfo(context.Background(), "wiki")
+	assert.ErrorIs(t, err, core.ErrTransient)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+}
+
+func TestRetrySkipsPermanentErrors(t *testing.T) {
+	var requests int32
+	server := newErrorServer(func(w http.ResponseWriter) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"code":131005,"msg":"not found"}`)
+	})
+	defer server.Close()
+
+	client := newTestClient(server.URL, fastRetry)
+	_, err := client.GetWikiNodeInfo(context.Background(), "wiki")
+	assert.ErrorIs(t, err, core.ErrNotFound)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestRetryRawRequest(t *testing.T) {
+	var requests int32
+	server := newErrorServer(func(w http.ResponseWriter) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, `{"code":0,"msg":"ok","data":{"items":[{"space_id":"s1","name":"知识库"}]}}`)
+	})
+	defer server.Close()
+
+	client := newTestClient(server.URL, fastRetry)
+	spaces, err := client.GetAllWikiSpaces(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, spaces, 1) {
+		assert.Equal(t, "知识库", spaces[0].Name)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestRetryTokenRequestNotNested(t *testing.T) {
+	var tokenRequests, apiRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/tenant_access_token/internal") {
+			atomic.AddInt32(&tokenRequests, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		atomic.AddInt32(&apiRequests, 1)
+	}))
+	defer server.Close()
+
+	// 获取令牌失败时只按照重试策略重试一轮，不会与接口请求的重试叠加
+	client := newTestClient(server.URL, fastRetry)
+	_, err := client.GetAllWikiSpaces(context.Background())
+	assert.ErrorIs(t, err, core.ErrTransient)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&tokenRequests))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&apiRequests))
+}
+
+func TestWikiNodeChildrenPartialPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/tenant_access_token/internal"):
+			fmt.Fprint(w, `{"code":0,"msg":"ok","tenant_access_token":"t-g1044abcdefghijk","expire":7200}`)
+		case strings.HasSuffix(r.URL.Path, "/get_node"):
+			fmt.Fprint(w, `{"code":0,"msg":"ok","data":{"node":{"space_id":"s1"}}}`)
+		case r.URL.Query().Get("page_token") == "":
+			fmt.Fprint(w, `{"code":0,"msg":"ok","data":{"items":[{"node_token":"n1"}],"page_token":"p2","has_more":true}}`)
+		default:
+			fmt.Fprint(w, `{"code":131006,"msg":"permission denied"}`)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, fastRetry)
+	nodes, err := client.GetWikiNodeChildren(context.Background(), "n0")
+	assert.ErrorIs(t, err, core.ErrPermissionDenied)
+	assert.Nil(t, nodes)
+}
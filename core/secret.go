@@ -0,0 +1,192 @@
+package core
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// 提供加密口令的环境变量，PassphraseCommandEnv 中的命令输出口令，可以对接 pass、钥匙串等密码管理工具
+const (
+	PassphraseEnv        = "FEISHU2MD_PASSPHRASE"
+	PassphraseCommandEnv = "FEISHU2MD_PASSPHRASE_COMMAND"
+)
+
+// 加密后的密钥格式为 enc:v1:<base64(salt|nonce|密文)>
+const encryptedSecretPrefix = "enc:v1:"
+
+// scrypt 参数，派生一次密钥约需几十毫秒
+const (
+	scryptN       = 1 << 15
+	scryptR       = 8
+	scryptP       = 1
+	secretKeySize = 32
+	secretSalt    = 16
+)
+
+var ErrPassphraseRequired = errors.New("配置中的密钥已加密，请设置环境变量 " + PassphraseEnv + " 或 " + PassphraseCommandEnv)
+
+// LookupPassphrase 返回环境变量或口令命令提供的加密口令，都未设置时返回空字符串
+func LookupPassphrase() (string, error) {
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	command := os.Getenv(PassphraseCommandEnv)
+	if command == "" {
+		return "", nil
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("执行口令命令失败: %w", err)
+	}
+	return strings.TrimRight(string(output), "\r\n"), nil
+}
+
+// IsEncryptedSecret 判断配置中的值是否为加密后的密钥
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, encryptedSecretPrefix)
+}
+
+// SecretCipher 使用口令加解密配置中的密钥，相同 salt 派生的密钥会被缓存
+type SecretCipher struct {
+	passphrase string
+	salt       []byte
+	keys       map[string]cipher.AEAD
+}
+
+func NewSecretCipher(passphrase string) *SecretCipher {
+	return &SecretCipher{passphrase: passphrase, keys: map[string]cipher.AEAD{}}
+}
+
+func (s *SecretCipher) aead(salt []byte) (cipher.AEAD, error) {
+	if aead, ok := s.keys[string(salt)]; ok {
+		return aead, nil
+	}
+	key, err := scrypt.Key([]byte(s.passphrase), salt, scryptN, scryptR, scryptP, secretKeySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	s.keys[string(salt)] = aead
+	return aead, nil
+}
+
+// Encrypt 加密密钥，空字符串和已加密的值保持不变
+// 同一个 SecretCipher 加密的值共用 salt，只需派生一次密钥
+func (s *SecretCipher) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" || IsEncryptedSecret(plaintext) {
+		return plaintext, nil
+	}
+	if s.salt == nil {
+		s.salt = make([]byte, secretSalt)
+		if _, err := rand.Read(s.salt); err != nil {
+			return "", err
+		}
+	}
+	aead, err := s.aead(s.salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	data := append(append([]byte{}, s.salt...), nonce...)
+	data = aead.Seal(data, nonce, []byte(plaintext), nil)
+	return encryptedSecretPrefix + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Decrypt 解密密钥，未加密的值原样返回
+func (s *SecretCipher) Decrypt(value string) (string, error) {
+	if !IsEncryptedSecret(value) {
+		return value, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(value, encryptedSecretPrefix))
+	if err != nil || len(data) < secretSalt {
+		return "", errors.New("加密的密钥格式无效")
+	}
+	aead, err := s.aead(data[:secretSalt])
+	if err != nil {
+		return "", err
+	}
+	data = data[secretSalt:]
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("加密的密钥格式无效")
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("解密密钥失败，口令不正确或配置已损坏")
+	}
+	return string(plaintext), nil
+}
+
+// HasEncryptedSecrets 判断配置中是否有加密的密钥
+func (conf *Config) HasEncryptedSecrets() bool {
+	encrypted := false
+	conf.transformSecrets(func(value string) (string, error) {
+		encrypted = encrypted || IsEncryptedSecret(value)
+		return value, nil
+	})
+	return encrypted
+}
+
+// EncryptSecrets 加密应用凭证和对象存储的密钥
+func (conf *Config) EncryptSecrets(passphrase string) error {
+	return conf.transformSecrets(NewSecretCipher(passphrase).Encrypt)
+}
+
+// DecryptSecrets 解密应用凭证和对象存储的密钥
+func (conf *Config) DecryptSecrets(passphrase string) error {
+	return conf.transformSecrets(NewSecretCipher(passphrase).Decrypt)
+}
+
+// 依次处理配置中的密钥，命名配置会被替换为新的 map，不影响共享同一个 map 的配置副本
+func (conf *Config) transformSecrets(fn func(string) (string, error)) error {
+	var err error
+	apply := func(value *string) {
+		if err == nil {
+			*value, err = fn(*value)
+		}
+	}
+	apply(&conf.Feishu.AppSecret)
+	apply(&conf.Output.AssetSink.SecretAccessKey)
+	if conf.Profiles == nil {
+		return err
+	}
+	profiles := make(map[string]Profile, len(conf.Profiles))
+	for _, name := range conf.ProfileNames() {
+		profile := conf.Profiles[name]
+		apply(&profile.Feishu.AppSecret)
+		if profile.Output != nil {
+			output := *profile.Output
+			apply(&output.AssetSink.SecretAccessKey)
+			profile.Output = &output
+		}
+		profiles[name] = profile
+	}
+	conf.Profiles = profiles
+	return err
+}
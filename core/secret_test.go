@@ -0,0 +1,74 @@
+package core_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretCipher(t *testing.T) {
+	encrypted, err := core.NewSecretCipher("passphrase").Encrypt("secret")
+	assert.NoError(t, err)
+	assert.True(t, core.IsEncryptedSecret(encrypted))
+	assert.NotContains(t, encrypted, "secret")
+
+	plaintext, err := core.NewSecretCipher("passphrase").Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", plaintext)
+
+	_, err = core.NewSecretCipher("wrong").Decrypt(encrypted)
+	assert.Error(t, err)
+
+	plaintext, err = core.NewSecretCipher("passphrase").Decrypt("plain")
+	assert.NoError(t, err)
+	assert.Equal(t, "plain", plaintext)
+}
+
+func TestConfigFileEncryptedSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := core.NewConfig("id", "app-secret")
+	config.Output.AssetSink.SecretAccessKey = "s3-secret"
+	config.Profiles = map[string]core.Profile{
+		"lark": {Feishu: core.FeishuConfig{AppId: "lark", AppSecret: "lark-secret"}},
+	}
+
+	t.Setenv(core.PassphraseEnv, "passphrase")
+	assert.NoError(t, config.WriteConfig2File(path))
+	assert.Equal(t, "app-secret", config.Feishu.AppSecret)
+	assert.Equal(t, "lark-secret", config.Profiles["lark"].Feishu.AppSecret)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	for _, secret := range []string{"app-secret", "s3-secret", "lark-secret"} {
+		assert.NotContains(t, string(data), secret)
+	}
+	assert.Equal(t, 3, strings.Count(string(data), "enc:v1:"))
+
+	loaded, err := core.ReadConfigFromFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, config, loaded)
+
+	t.Setenv(core.PassphraseEnv, "")
+	_, err = core.ReadConfigFromFile(path)
+	assert.ErrorIs(t, err, core.ErrPassphraseRequired)
+
+	t.Setenv(core.PassphraseCommandEnv, "echo passphrase")
+	loaded, err = core.ReadConfigFromFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "lark-secret", loaded.Profiles["lark"].Feishu.AppSecret)
+}
+
+func TestConfigFilePermissions(t *testing.T) {
+	if os.PathSeparator != '/' {
+		t.Skip("Windows 不支持 Unix 文件权限")
+	}
+	path := writeTestFile(t, filepath.Join(t.TempDir(), "config.json"), "{}")
+	assert.NoError(t, core.NewConfig("id", "secret").WriteConfig2File(path))
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
@@ -0,0 +1,292 @@
+package core
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/chyroc/lark"
+)
+
+// 电子表格导出格式
+const (
+	SheetFormatMarkdown = "md"
+	SheetFormatCSV      = "csv"
+	SheetFormatXLSX     = "xlsx"
+)
+
+// 每次读取的最大行数，避免单次返回数据超过接口限制
+const sheetRowsPerRequest = 1000
+
+// SheetCell 工作表中的单元格
+type SheetCell struct {
+	Text     string
+	IsNumber bool
+}
+
+// SheetMerge 合并单元格范围，下标从 0 开始
+type SheetMerge struct {
+	Row     int
+	Col     int
+	RowSpan int
+	ColSpan int
+}
+
+// Sheet 电子表格中的一个工作表
+type Sheet struct {
+	SheetID string
+	Title   string
+	Rows    [][]SheetCell
+	Merges  []SheetMerge
+}
+
+// Spreadsheet 电子表格及其全部工作表
+type Spreadsheet struct {
+	Token  string
+	Title  string
+	Sheets []*Sheet
+}
+
+// GetSpreadsheet 获取电子表格的元信息和所有工作表的值
+func (c *Client) GetSpreadsheet(ctx context.Context, spreadsheetToken string) (*Spreadsheet, error) {
+	meta, err := c.GetSheetMeta(ctx, spreadsheetToken)
+	if err != nil {
+		return nil, err
+	}
+
+	spreadsheet := &Spreadsheet{Token: spreadsheetToken}
+	if meta.Properties != nil {
+		spreadsheet.Title = meta.Properties.Title
+	}
+
+	for _, metaSheet := range meta.Sheets {
+		// 含有 BlockInfo 的工作表不是普通表格（例如内嵌的多维表格），无法读取单元格
+		if metaSheet.BlockInfo != nil {
+			continue
+		}
+		// 没有行或列的空工作表没有可读取的范围，不导出
+		if metaSheet.RowCount <= 0 || metaSheet.ColumnCount <= 0 {
+			continue
+		}
+
+		var values [][]interface{}
+		for start := int64(1); start <= metaSheet.RowCount; start += sheetRowsPerRequest {
+			end := start + sheetRowsPerRequest - 1
+			if end > metaSheet.RowCount {
+				end = metaSheet.RowCount
+			}
+			valueRange := fmt.Sprintf("%s!A%d:%s%d",
+				metaSheet.SheetID, start, SheetColumnName(int(metaSheet.ColumnCount)-1), end)
+			chunk, err := c.GetSheetValues(ctx, spreadsheetToken, valueRange)
+			if err != nil {
+				return nil, fmt.Errorf("读取工作表 %s 失败: %w", metaSheet.Title, err)
+			}
+			values = append(values, chunk...)
+		}
+
+		spreadsheet.Sheets = append(spreadsheet.Sheets, NewSheet(metaSheet, values))
+	}
+
+	return spreadsheet, nil
+}
+
+// NewSheet 根据工作表元信息和读取到的原始值构建工作表，并去除末尾的空行和空列
+func NewSheet(meta *lark.GetSheetMetaRespSheet, values [][]interface{}) *Sheet {
+	sheet := &Sheet{
+		SheetID: meta.SheetID,
+		Title:   meta.Title,
+	}
+
+	for _, row := range values {
+		cells := make([]SheetCell, len(row))
+		for i, value := range row {
+			cells[i] = newSheetCell(value)
+		}
+		sheet.Rows = append(sheet.Rows, cells)
+	}
+
+	for _, merge := range meta.Merges {
+		sheet.Merges = append(sheet.Merges, SheetMerge{
+			Row:     int(merge.StartRowIndex),
+			Col:     int(merge.StartColumnIndex),
+			RowSpan: int(merge.RowCount),
+			ColSpan: int(merge.ColumnCount),
+		})
+	}
+
+	sheet.trim()
+	return sheet
+}
+
+// 将接口返回的单元格值转换为文本
+func newSheetCell(value interface{}) SheetCell {
+	switch v := value.(type) {
+	case nil:
+		return SheetCell{}
+	case string:
+		return SheetCell{Text: v}
+	case float64:
+		return SheetCell{Text: strconv.FormatFloat(v, 'f', -1, 64), IsNumber: true}
+	case bool:
+		return SheetCell{Text: strings.ToUpper(strconv.FormatBool(v))}
+	case map[string]interface{}:
+		// 链接、@人、@文档和公式等对象都带有 text 字段
+		if text, ok := v["text"].(string); ok {
+			return SheetCell{Text: text}
+		}
+		if values, ok := v["values"].([]interface{}); ok {
+			parts := make([]string, 0, len(values))
+			for _, item := range values {
+				parts = append(parts, newSheetCell(item).Text)
+			}
+			return SheetCell{Text: strings.Join(parts, ",")}
+		}
+		return SheetCell{}
+	case []interface{}:
+		// 富文本单元格由多个片段组成
+		buf := new(strings.Builder)
+		for _, segment := range v {
+			buf.WriteString(newSheetCell(segment).Text)
+		}
+		return SheetCell{Text: buf.String()}
+	default:
+		return SheetCell{Text: fmt.Sprint(v)}
+	}
+}
+
+func (s *Sheet) trim() {
+	for len(s.Rows) > 0 && isEmptyRow(s.Rows[len(s.Rows)-1]) {
+		s.Rows = s.Rows[:len(s.Rows)-1]
+	}
+	width := s.Width()
+	for i, row := range s.Rows {
+		if len(row) > width {
+			s.Rows[i] = row[:width]
+		}
+	}
+}
+
+func isEmptyRow(row []SheetCell) bool {
+	for _, cell := range row {
+		if cell.Text != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Width 返回工作表中最后一个非空单元格所在的列数
+func (s *Sheet) Width() int {
+	width := 0
+	for _, row := range s.Rows {
+		for i := len(row) - 1; i >= width; i-- {
+			if row[i].Text != "" {
+				width = i + 1
+				break
+			}
+		}
+	}
+	return width
+}
+
+// grid 返回补齐为矩形的单元格文本
+func (s *Sheet) grid() [][]string {
+	width := s.Width()
+	grid := make([][]string, len(s.Rows))
+	for i, row := range s.Rows {
+		grid[i] = make([]string, width)
+		for j := 0; j < width && j < len(row); j++ {
+			grid[i][j] = row[j].Text
+		}
+	}
+	return grid
+}
+
+// SheetColumnName 将从 0 开始的列下标转换为 A、B、...、AA 形式的列名
+func SheetColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// ToCSV 将工作表导出为 CSV，合并单元格的值只保留在左上角的单元格中
+func (s *Sheet) ToCSV() (string, error) {
+	buf := new(strings.Builder)
+	writer := csv.NewWriter(buf)
+	if err := writer.WriteAll(s.grid()); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ToMarkdown 将工作表导出为 Markdown 表格，含有合并单元格时与文档表格一样输出 HTML 表格
+func (s *Sheet) ToMarkdown() string {
+	grid := s.grid()
+	if len(grid) == 0 || s.Width() == 0 {
+		return ""
+	}
+
+	if len(s.Merges) == 0 {
+		for _, row := range grid {
+			for j, text := range row {
+				text = strings.ReplaceAll(text, "|", `\|`)
+				row[j] = strings.ReplaceAll(text, "\n", "<br/>")
+			}
+		}
+		return renderMarkdownTable(grid)
+	}
+
+	mergeMap := map[[2]int]SheetMerge{}
+	covered := map[[2]int]bool{}
+	for _, merge := range s.Merges {
+		mergeMap[[2]int{merge.Row, merge.Col}] = merge
+		for r := merge.Row; r < merge.Row+merge.RowSpan; r++ {
+			for c := merge.Col; c < merge.Col+merge.ColSpan; c++ {
+				covered[[2]int{r, c}] = true
+			}
+		}
+	}
+
+	buf := new(strings.Builder)
+	buf.WriteString("<table>\n")
+	for rowIndex, row := range grid {
+		buf.WriteString("<tr>\n")
+		for colIndex, text := range row {
+			key := [2]int{rowIndex, colIndex}
+			content := strings.ReplaceAll(html.EscapeString(text), "\n", "<br/>")
+			if merge, ok := mergeMap[key]; ok {
+				attributes := ""
+				if merge.RowSpan > 1 {
+					attributes += fmt.Sprintf(` rowspan="%d"`, merge.RowSpan)
+				}
+				if merge.ColSpan > 1 {
+					attributes += fmt.Sprintf(` colspan="%d"`, merge.ColSpan)
+				}
+				buf.WriteString(fmt.Sprintf("<td%s>%s</td>", attributes, content))
+			} else if !covered[key] {
+				buf.WriteString(fmt.Sprintf("<td>%s</td>", content))
+			}
+		}
+		buf.WriteString("</tr>\n")
+	}
+	buf.WriteString("</table>\n")
+	return buf.String()
+}
+
+// ToMarkdown 将电子表格导出为 Markdown，每个工作表对应一个二级标题
+func (s *Spreadsheet) ToMarkdown() string {
+	buf := new(strings.Builder)
+	buf.WriteString("# " + s.Title + "\n\n")
+	for _, sheet := range s.Sheets {
+		buf.WriteString("## " + sheet.Title + "\n\n")
+		buf.WriteString(sheet.ToMarkdown())
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
@@ -0,0 +1,135 @@
+package core_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/chyroc/lark"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSheet() *core.Sheet {
+	meta := &lark.GetSheetMetaRespSheet{
+		SheetID: "a1b2c3",
+		Title:   "Sheet1",
+		Merges: []*lark.GetSheetMetaRespSheetMerge{
+			{StartRowIndex: 1, StartColumnIndex: 0, RowCount: 2, ColumnCount: 1},
+		},
+	}
+	values := [][]interface{}{
+		{"name", "score", nil},
+		{"alice", 3.5, nil},
+		{nil, map[string]interface{}{"type": "url", "text": "link", "link": "https://example.com"}, nil},
+		{nil, nil, nil},
+	}
+	return core.NewSheet(meta, values)
+}
+
+func TestSheetColumnName(t *testing.T) {
+	assert.Equal(t, "A", core.SheetColumnName(0))
+	assert.Equal(t, "Z", core.SheetColumnName(25))
+	assert.Equal(t, "AA", core.SheetColumnName(26))
+	assert.Equal(t, "AZ", core.SheetColumnName(51))
+	assert.Equal(t, "ZZ", core.SheetColumnName(701))
+	assert.Equal(t, "AAA", core.SheetColumnName(702))
+}
+
+func TestSheetToCSV(t *testing.T) {
+	sheet := newTestSheet()
+	assert.Equal(t, 2, sheet.Width())
+	content, err := sheet.ToCSV()
+	assert.NoError(t, err)
+	assert.Equal(t, "name,score\nalice,3.5\n,link\n", content)
+}
+
+func TestSheetToMarkdown(t *testing.T) {
+	sheet := newTestSheet()
+	assert.Equal(t, "<table>\n"+
+		"<tr>\n<td>name</td><td>score</td></tr>\n"+
+		"<tr>\n<td rowspan=\"2\">alice</td><td>3.5</td></tr>\n"+
+		"<tr>\n<td>link</td></tr>\n"+
+		"</table>\n", sheet.ToMarkdown())
+
+	sheet.Merges = nil
+	assert.Contains(t, sheet.ToMarkdown(), "| alice |   3.5 |")
+}
+
+func TestWriteXLSX(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := core.WriteXLSX(buf, []*core.Sheet{newTestSheet()})
+	assert.NoError(t, err)
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	files := map[string]string{}
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		assert.NoError(t, err)
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+
+	assert.Contains(t, files, "[Content_Types].xml")
+	assert.Contains(t, files["xl/workbook.xml"], `<sheet name="Sheet1" sheetId="1" r:id="rId1"/>`)
+	worksheet := files["xl/worksheets/sheet1.xml"]
+	assert.True(t, strings.Contains(worksheet, `<c r="B2"><v>3.5</v></c>`))
+	assert.True(t, strings.Contains(worksheet, `<mergeCell ref="A2:A3"/>`))
+}
+
+func TestWriteXLSXUniqueSheetNames(t *testing.T) {
+	var sheets []*core.Sheet
+	for _, title := range []string{"A", "a_3", "A", "a/b", "a_b"} {
+		sheet := newTestSheet()
+		sheet.Title = title
+		sheets = append(sheets, sheet)
+	}
+	buf := new(bytes.Buffer)
+	assert.NoError(t, core.WriteXLSX(buf, sheets))
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	rc, err := reader.Open("xl/workbook.xml")
+	assert.NoError(t, err)
+	workbook, _ := io.ReadAll(rc)
+	rc.Close()
+
+	// 添加的 _<id> 后缀与其他工作表重名时继续添加序号
+	for _, name := range []string{"A", "a_3", "A_3_2", "a_b", "a_b_5"} {
+		assert.Contains(t, string(workbook), `<sheet name="`+name+`"`)
+	}
+}
+
+func TestGetSpreadsheetSkipsEmptyAndEmbeddedSheets(t *testing.T) {
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/tenant_access_token/internal"):
+			fmt.Fprint(w, `{"code":0,"msg":"ok","tenant_access_token":"t-g1044abcdefghijk","expire":7200}`)
+		case strings.HasSuffix(r.URL.Path, "/metainfo"):
+			fmt.Fprint(w, `{"code":0,"msg":"ok","data":{"properties":{"title":"Report"},"sheets":[
+				{"sheetId":"bt","title":"Base","rowCount":0,"columnCount":0,"blockInfo":{"blockToken":"x","blockType":"BITABLE_BLOCK"}},
+				{"sheetId":"empty","title":"Empty","rowCount":0,"columnCount":0},
+				{"sheetId":"s1","title":"Data","rowCount":2,"columnCount":2}]}}`)
+		default:
+			ranges = append(ranges, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+			fmt.Fprint(w, `{"code":0,"msg":"ok","data":{"valueRange":{"values":[["a","b"],[1,2]]}}}`)
+		}
+	}))
+	defer server.Close()
+
+	spreadsheet, err := newTestClient(server.URL).GetSpreadsheet(context.Background(), "shtcn")
+	assert.NoError(t, err)
+	assert.Equal(t, "Report", spreadsheet.Title)
+	assert.Len(t, spreadsheet.Sheets, 1)
+	assert.Equal(t, "Data", spreadsheet.Sheets[0].Title)
+	// 只读取普通工作表，不会请求 empty!A1: 这样的无效范围
+	assert.Equal(t, []string{"s1!A1:B2"}, ranges)
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// 图片的保存位置
+const (
+	AssetSinkLocal = "local"
+	AssetSinkS3    = "s3"
+)
+
+// AssetSink 保存导出的图片，并返回 Markdown 中引用图片的链接
+type AssetSink interface {
+	// Put 保存文件内容，name 为以 / 分隔的相对路径
+	Put(ctx context.Context, name string, data []byte) (string, error)
+}
+
+// AssetSinkConfig 图片保存位置的配置
+type AssetSinkConfig struct {
+	// Type 为 local 或 s3，为空时保存到本地
+	Type string `json:"type"`
+	// Endpoint S3 兼容服务的地址，如 s3.amazonaws.com 或 localhost:9000，不包含协议
+	Endpoint        string `json:"endpoint"`
+	Region          string `json:"region"`
+	Bucket          string `json:"bucket"`
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	UseSSL          bool   `json:"use_ssl"`
+	// PathStyle 使用 endpoint/bucket/key 形式的地址访问存储桶，MinIO 通常需要开启
+	PathStyle bool `json:"path_style"`
+	// Prefix 对象键的前缀
+	Prefix string `json:"prefix"`
+	// URLTemplate 上传后图片的访问地址，可以使用 {endpoint}、{bucket} 和 {key} 占位符
+	// 例如 https://cdn.example.com/{key}，为空时使用存储服务的地址
+	URLTemplate string `json:"url_template"`
+}
+
+// LocalSink 将图片保存到本地目录，链接为 LinkPrefix 加上文件名
+type LocalSink struct {
+	Dir        string
+	LinkPrefix string
+}
+
+// NewLocalSink 创建保存到本地目录的 AssetSink，目录不存在时自动创建
+func NewLocalSink(dir, linkPrefix string) (*LocalSink, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalSink{Dir: dir, LinkPrefix: linkPrefix}, nil
+}
+
+func (s *LocalSink) Put(ctx context.Context, name string, data []byte) (string, error) {
+	filename := filepath.Join(s.Dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filename, data, 0o644); err != nil {
+		return "", err
+	}
+	return path.Join(s.LinkPrefix, name), nil
+}
+
+// S3Sink 将图片上传到 S3 兼容的对象存储
+type S3Sink struct {
+	config AssetSinkConfig
+	client *minio.Client
+}
+
+// NewS3Sink 创建上传到对象存储的 AssetSink
+// 配置中没有访问密钥时，从 AWS_ACCESS_KEY_ID 和 AWS_SECRET_ACCESS_KEY 环境变量读取
+func NewS3Sink(config AssetSinkConfig) (*S3Sink, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, fmt.Errorf("对象存储需要配置 endpoint 和 bucket")
+	}
+	creds := credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, "")
+	if config.AccessKeyID == "" {
+		creds = credentials.NewEnvAWS()
+	}
+	lookup := minio.BucketLookupAuto
+	if config.PathStyle {
+		lookup = minio.BucketLookupPath
+	}
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:        creds,
+		Secure:       config.UseSSL,
+		Region:       config.Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3Sink{config: config, client: client}, nil
+}
+
+func (s *S3Sink) Put(ctx context.Context, name string, data []byte) (string, error) {
+	key := path.Join(s.config.Prefix, name)
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	_, err := s.client.PutObject(ctx, s.config.Bucket, key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("上传 %s 到存储桶 %s 失败: %w", key, s.config.Bucket, err)
+	}
+	return s.URL(key), nil
+}
+
+// URL 按照配置的模板生成对象的访问地址
+func (s *S3Sink) URL(key string) string {
+	template := s.config.URLTemplate
+	if template == "" {
+		scheme := "http"
+		if s.config.UseSSL {
+			scheme = "https"
+		}
+		template = scheme + "://{endpoint}/{bucket}/{key}"
+		if !s.config.PathStyle {
+			template = scheme + "://{bucket}.{endpoint}/{key}"
+		}
+	}
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.NewReplacer(
+		"{endpoint}", s.config.Endpoint,
+		"{bucket}", s.config.Bucket,
+		"{key}", strings.Join(segments, "/"),
+	).Replace(template)
+}
@@ -0,0 +1,105 @@
+package core_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalSink(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "guide_images")
+	sink, err := core.NewLocalSink(dir, "guide_images")
+	assert.NoError(t, err)
+
+	link, err := sink.Put(context.Background(), "img.png", []byte("png"))
+	assert.NoError(t, err)
+	assert.Equal(t, "guide_images/img.png", link)
+	data, err := os.ReadFile(filepath.Join(dir, "img.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, "png", string(data))
+}
+
+func TestS3SinkURL(t *testing.T) {
+	config := core.AssetSinkConfig{Endpoint: "s3.example.com", Bucket: "blog", AccessKeyID: "key", SecretAccessKey: "secret", UseSSL: true}
+	sink, err := core.NewS3Sink(config)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://blog.s3.example.com/doc/a%20b.png", sink.URL("doc/a b.png"))
+
+	config.PathStyle = true
+	config.UseSSL = false
+	sink, err = core.NewS3Sink(config)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://s3.example.com/blog/doc/img.png", sink.URL("doc/img.png"))
+
+	config.URLTemplate = "https://cdn.example.com/{bucket}/{key}"
+	sink, err = core.NewS3Sink(config)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://cdn.example.com/blog/doc/img.png", sink.URL("doc/img.png"))
+
+	_, err = core.NewS3Sink(core.AssetSinkConfig{Endpoint: "s3.example.com"})
+	assert.Error(t, err)
+}
+
+func TestS3SinkPut(t *testing.T) {
+	uploaded := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256"))
+		// 不使用 HTTPS 时内容按块签名上传，只检查内容长度
+		io.Copy(io.Discard, r.Body)
+		uploaded[r.URL.Path] = r.Header.Get("Content-Type") + ":" + r.Header.Get("X-Amz-Decoded-Content-Length")
+		w.Header().Set("ETag", `"etag"`)
+	}))
+	defer server.Close()
+
+	endpoint := strings.TrimPrefix(server.URL, "http://")
+	sink, err := core.NewS3Sink(core.AssetSinkConfig{
+		Endpoint:        endpoint,
+		Region:          "us-east-1",
+		Bucket:          "blog",
+		AccessKeyID:     "minioadmin",
+		SecretAccessKey: "minioadmin",
+		PathStyle:       true,
+		Prefix:          "images/doc",
+		URLTemplate:     "https://cdn.example.com/{key}",
+	})
+	assert.NoError(t, err)
+
+	link, err := sink.Put(context.Background(), "img.png", []byte("png"))
+	assert.NoError(t, err)
+	assert.Equal(t, "https://cdn.example.com/images/doc/img.png", link)
+	assert.Equal(t, map[string]string{"/blog/images/doc/img.png": "image/png:3"}, uploaded)
+}
+
+// 设置 FEISHU2MD_TEST_S3_ENDPOINT 等环境变量后，上传到真实的对象存储，例如本地启动的 MinIO：
+// docker run -p 9000:9000 minio/minio server /data
+func TestS3SinkMinIO(t *testing.T) {
+	endpoint := os.Getenv("FEISHU2MD_TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("未设置 FEISHU2MD_TEST_S3_ENDPOINT")
+	}
+	sink, err := core.NewS3Sink(core.AssetSinkConfig{
+		Endpoint:        endpoint,
+		Bucket:          os.Getenv("FEISHU2MD_TEST_S3_BUCKET"),
+		AccessKeyID:     os.Getenv("FEISHU2MD_TEST_S3_ACCESS_KEY"),
+		SecretAccessKey: os.Getenv("FEISHU2MD_TEST_S3_SECRET_KEY"),
+		PathStyle:       true,
+		Prefix:          "feishu2md-test",
+	})
+	assert.NoError(t, err)
+
+	link, err := sink.Put(context.Background(), "hello.txt", []byte("hello"))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(link, "/feishu2md-test/hello.txt"))
+}
@@ -0,0 +1,366 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 增量同步的状态文件和已删除文档的归档目录，都位于输出目录下
+const (
+	SyncStateFile  = ".feishu2md-sync.json"
+	SyncArchiveDir = ".archive"
+)
+
+// SyncEntry 一个已导出文档的记录，路径都相对于输出目录，使用 / 分隔
+type SyncEntry struct {
+	Space    string   `json:"space,omitempty"` // 文档所在的知识空间，不是从知识库导出时为空
+	Title    string   `json:"title"`
+	Revision int64    `json:"revision"`
+	Path     string   `json:"path"`             // Markdown 文件
+	Assets   []string `json:"assets,omitempty"` // 保存在本地的图片
+	Checksum string   `json:"checksum"`         // Markdown 文件内容的 SHA-256
+	// AssetStore 图片保存在共享图片库时为图片库目录，Markdown 中的链接相对于文件所在目录
+	AssetStore string    `json:"asset_store,omitempty"`
+	SyncedAt   time.Time `json:"synced_at"`
+}
+
+// SyncRecord 文档本次导出的结果，路径为文件系统路径
+type SyncRecord struct {
+	Space      string
+	Title      string
+	Revision   int64
+	Path       string   // Markdown 文件
+	Assets     []string // 保存在本地、只属于这个文档的文件
+	AssetStore string   // 使用的共享图片库目录
+}
+
+// SyncState 记录输出目录中每个文档导出时的版本和文件，用于跳过没有更新的文档
+type SyncState struct {
+	Dir       string                `json:"-"`
+	Documents map[string]*SyncEntry `json:"documents"` // 文档 token → 记录
+
+	mu sync.Mutex
+}
+
+var syncStates = struct {
+	sync.Mutex
+	states map[string]*SyncState
+}{states: map[string]*SyncState{}}
+
+// OpenSyncState 读取输出目录中的同步状态，同一目录在进程内共享同一个实例
+func OpenSyncState(dir string) (*SyncState, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	syncStates.Lock()
+	defer syncStates.Unlock()
+	if state, ok := syncStates.states[dir]; ok {
+		return state, nil
+	}
+
+	state := &SyncState{Dir: dir, Documents: map[string]*SyncEntry{}}
+	data, err := os.ReadFile(filepath.Join(dir, SyncStateFile))
+	if err == nil {
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if state.Documents == nil {
+		state.Documents = map[string]*SyncEntry{}
+	}
+	// 同步时会删除和移动记录中的文件，记录的路径必须位于输出目录中
+	for token, entry := range state.Documents {
+		if entry == nil {
+			return nil, fmt.Errorf("同步状态中文档 %s 的记录无效", token)
+		}
+		for _, file := range append([]string{entry.Path}, entry.Assets...) {
+			if !isLocalPath(file) {
+				return nil, fmt.Errorf("同步状态中文档 %s 的路径 %s 不在输出目录中", token, file)
+			}
+		}
+	}
+	syncStates.states[dir] = state
+	return state, nil
+}
+
+// Lookup 返回文档的记录
+func (s *SyncState) Lookup(token string) (SyncEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.Documents[token]
+	if !ok {
+		return SyncEntry{}, false
+	}
+	return *entry, true
+}
+
+// PathOwner 返回上次导出到这个路径的文档，不区分大小写，路径不属于任何文档时返回空字符串
+func (s *SyncState) PathOwner(path string) string {
+	rel, err := s.rel(path)
+	if err != nil {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token, entry := range s.Documents {
+		if strings.EqualFold(entry.Path, rel) {
+			return token
+		}
+	}
+	return ""
+}
+
+// Unchanged 文档版本与上次导出时相同，导出的 Markdown 文件没有被删除或修改，并且本地图片都还在
+func (s *SyncState) Unchanged(token string, revision int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.Documents[token]
+	if !ok || entry.Revision != revision {
+		return false
+	}
+	for _, asset := range entry.Assets {
+		if _, err := os.Stat(s.abs(asset)); err != nil {
+			return false
+		}
+	}
+	checksum, err := fileChecksum(s.abs(entry.Path))
+	return err == nil && checksum == entry.Checksum
+}
+
+// Relocate 将没有更新的文档移动到新的 Markdown 路径，文档重命名或在知识库中移动时使用
+// 图片随 Markdown 文件一起移动，保持相对链接有效；目录改变而图片不在 Markdown 所在目录下，
+// 或者使用了共享图片库时无法移动，返回 false，需要重新导出
+func (s *SyncState) Relocate(token, mdPath string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.Documents[token]
+	if !ok {
+		return false, nil
+	}
+	newPath, err := s.rel(mdPath)
+	if err != nil {
+		return false, err
+	}
+	if newPath == entry.Path {
+		return true, nil
+	}
+
+	oldDir, newDir := filepath.Dir(s.abs(entry.Path)), filepath.Dir(s.abs(newPath))
+	if oldDir != newDir && entry.AssetStore != "" {
+		return false, nil
+	}
+	moves := map[string]string{s.abs(entry.Path): s.abs(newPath)}
+	var assets []string
+	for _, asset := range entry.Assets {
+		target := asset
+		if oldDir != newDir {
+			rel, err := filepath.Rel(oldDir, s.abs(asset))
+			if err != nil || !isLocalPath(rel) {
+				return false, nil
+			}
+			if target, err = s.rel(filepath.Join(newDir, rel)); err != nil {
+				return false, err
+			}
+			moves[s.abs(asset)] = s.abs(target)
+		}
+		assets = append(assets, target)
+	}
+
+	for from, to := range moves {
+		if err := moveFile(from, to); err != nil {
+			return false, err
+		}
+	}
+	for from := range moves {
+		removeEmptyDirs(filepath.Dir(from), s.Dir)
+	}
+	entry.Path = newPath
+	entry.Assets = assets
+	return true, s.save()
+}
+
+// Record 记录文档本次导出的文件，并删除上次导出时留下、本次不再使用的文件
+// Markdown 文件和图片需要位于输出目录中，共享图片库可以在其他目录
+func (s *SyncState) Record(token string, record SyncRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := &SyncEntry{Space: record.Space, Title: record.Title, Revision: record.Revision, SyncedAt: time.Now()}
+	var err error
+	if entry.Path, err = s.localRel(record.Path); err != nil {
+		return err
+	}
+	if entry.Checksum, err = fileChecksum(record.Path); err != nil {
+		return err
+	}
+	if record.AssetStore != "" {
+		if entry.AssetStore, err = s.rel(record.AssetStore); err != nil {
+			return err
+		}
+	}
+	current := map[string]bool{entry.Path: true}
+	for _, asset := range record.Assets {
+		rel, err := s.localRel(asset)
+		if err != nil {
+			return err
+		}
+		if !current[rel] {
+			current[rel] = true
+			entry.Assets = append(entry.Assets, rel)
+		}
+	}
+	sort.Strings(entry.Assets)
+
+	if old, ok := s.Documents[token]; ok {
+		for _, file := range append([]string{old.Path}, old.Assets...) {
+			if current[file] || !isLocalPath(file) || s.sharedLocked(token, file) {
+				continue
+			}
+			if err := os.Remove(s.abs(file)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			removeEmptyDirs(filepath.Dir(s.abs(file)), s.Dir)
+		}
+	}
+	s.Documents[token] = entry
+	return s.save()
+}
+
+// Prune 删除知识空间 space 中不在 keep 里的文档导出的文件，archive 为 true 时移动到归档目录，返回被删除的文档 token
+// 其他文档也在使用的图片不会被删除
+func (s *SyncState) Prune(space string, keep map[string]bool, archive bool) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var removed []string
+	for token, entry := range s.Documents {
+		if entry.Space == space && !keep[token] {
+			removed = append(removed, token)
+		}
+	}
+	sort.Strings(removed)
+
+	for _, token := range removed {
+		entry := s.Documents[token]
+		for _, file := range append([]string{entry.Path}, entry.Assets...) {
+			if !isLocalPath(file) || s.sharedLocked(token, file) {
+				continue
+			}
+			var err error
+			if archive {
+				err = moveFile(s.abs(file), filepath.Join(s.Dir, SyncArchiveDir, filepath.FromSlash(file)))
+			} else {
+				err = os.Remove(s.abs(file))
+			}
+			if err != nil && !os.IsNotExist(err) {
+				return removed, err
+			}
+			removeEmptyDirs(filepath.Dir(s.abs(file)), s.Dir)
+		}
+		delete(s.Documents, token)
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	return removed, s.save()
+}
+
+// 其他文档是否也引用了这个文件，调用时需要持有锁
+func (s *SyncState) sharedLocked(token, file string) bool {
+	for other, entry := range s.Documents {
+		if other == token {
+			continue
+		}
+		if entry.Path == file {
+			return true
+		}
+		for _, asset := range entry.Assets {
+			if asset == file {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *SyncState) abs(rel string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(rel))
+}
+
+func (s *SyncState) rel(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(s.Dir, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// 返回输出目录中文件的相对路径，文件不在输出目录中时返回错误
+func (s *SyncState) localRel(path string) (string, error) {
+	rel, err := s.rel(path)
+	if err != nil {
+		return "", err
+	}
+	if !isLocalPath(rel) {
+		return "", fmt.Errorf("文件 %s 不在输出目录 %s 中", path, s.Dir)
+	}
+	return rel, nil
+}
+
+// 先写入临时文件再重命名，调用时需要持有锁
+func (s *SyncState) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(s.Dir, SyncStateFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.Dir, SyncStateFile))
+}
+
+func fileChecksum(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// 相对路径没有指向上级目录
+// 相对路径是否位于输出目录中，绝对路径和以 .. 开头的路径都不是
+func isLocalPath(rel string) bool {
+	return filepath.IsLocal(filepath.FromSlash(rel))
+}
+
+func moveFile(from, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0o755); err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
+
+// 删除 dir 及其上级中的空目录，直到 root 为止
+func removeEmptyDirs(dir, root string) {
+	for dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,126 @@
+package core_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestFile(t *testing.T, path, content string) string {
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestSyncState(t *testing.T) {
+	dir := t.TempDir()
+	state, err := core.OpenSyncState(dir)
+	assert.NoError(t, err)
+
+	md := writeTestFile(t, filepath.Join(dir, "A", "Guide.md"), "![](Guide_images/img.png)")
+	img := writeTestFile(t, filepath.Join(dir, "A", "Guide_images", "img.png"), "png")
+	assert.NoError(t, state.Record("doc", core.SyncRecord{Space: "space", Title: "Guide", Revision: 3, Path: md, Assets: []string{img}}))
+
+	assert.True(t, state.Unchanged("doc", 3))
+	assert.False(t, state.Unchanged("doc", 4))
+	assert.False(t, state.Unchanged("other", 3))
+
+	// 同一目录下重命名只移动 Markdown 文件
+	renamed := filepath.Join(dir, "A", "Guide v2.md")
+	ok, err := state.Relocate("doc", renamed)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoFileExists(t, md)
+	assert.FileExists(t, renamed)
+	assert.FileExists(t, img)
+
+	// 移动到其他目录时图片一起移动，旧目录被清理
+	moved := filepath.Join(dir, "B", "C", "Guide v2.md")
+	ok, err = state.Relocate("doc", moved)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.FileExists(t, moved)
+	assert.FileExists(t, filepath.Join(dir, "B", "C", "Guide_images", "img.png"))
+	assert.NoDirExists(t, filepath.Join(dir, "A"))
+	entry, _ := state.Lookup("doc")
+	assert.Equal(t, "B/C/Guide v2.md", entry.Path)
+	assert.Equal(t, []string{"B/C/Guide_images/img.png"}, entry.Assets)
+	assert.True(t, state.Unchanged("doc", 3))
+
+	// 图片被删除后需要重新导出
+	movedImg := filepath.Join(dir, "B", "C", "Guide_images", "img.png")
+	assert.NoError(t, os.Remove(movedImg))
+	assert.False(t, state.Unchanged("doc", 3))
+	writeTestFile(t, movedImg, "png")
+	assert.True(t, state.Unchanged("doc", 3))
+
+	// 本地修改过的文件需要重新导出
+	writeTestFile(t, moved, "edited")
+	assert.False(t, state.Unchanged("doc", 3))
+
+	// 重新导出到新位置后删除上次导出的文件
+	md = writeTestFile(t, filepath.Join(dir, "Guide.md"), "new")
+	assert.NoError(t, state.Record("doc", core.SyncRecord{Space: "space", Title: "Guide", Revision: 4, Path: md}))
+	assert.NoDirExists(t, filepath.Join(dir, "B"))
+
+	// 重新打开时读取保存的状态
+	data, err := os.ReadFile(filepath.Join(dir, core.SyncStateFile))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"revision": 4`)
+}
+
+func TestSyncStatePrune(t *testing.T) {
+	dir := t.TempDir()
+	state, err := core.OpenSyncState(dir)
+	assert.NoError(t, err)
+
+	kept := writeTestFile(t, filepath.Join(dir, "kept.md"), "kept")
+	archived := writeTestFile(t, filepath.Join(dir, "sub", "archived.md"), "archived")
+	other := writeTestFile(t, filepath.Join(dir, "other.md"), "other")
+	assert.NoError(t, state.Record("kept", core.SyncRecord{Space: "space", Path: kept}))
+	assert.NoError(t, state.Record("archived", core.SyncRecord{Space: "space", Path: archived}))
+	assert.NoError(t, state.Record("other", core.SyncRecord{Space: "other-space", Path: other}))
+
+	// 只清理同一个知识空间中的文档
+	removed, err := state.Prune("space", map[string]bool{"kept": true}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"archived"}, removed)
+	assert.FileExists(t, filepath.Join(dir, core.SyncArchiveDir, "sub", "archived.md"))
+	assert.NoDirExists(t, filepath.Join(dir, "sub"))
+	assert.FileExists(t, kept)
+	assert.FileExists(t, other)
+
+	removed, err = state.Prune("other-space", nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"other"}, removed)
+	assert.NoFileExists(t, other)
+	_, ok := state.Lookup("other")
+	assert.False(t, ok)
+}
+
+func TestSyncStateRejectsOutsidePaths(t *testing.T) {
+	root := t.TempDir()
+	outside := writeTestFile(t, filepath.Join(root, "outside.txt"), "keep")
+
+	// 状态文件中指向输出目录之外的记录被拒绝，不会在同步时删除这些文件
+	for _, path := range []string{"../outside.txt", outside} {
+		dir := filepath.Join(t.TempDir(), "out")
+		writeTestFile(t, filepath.Join(dir, core.SyncStateFile), `{"documents":{"doc":{"path":"`+filepath.ToSlash(path)+`"}}}`)
+		_, err := core.OpenSyncState(dir)
+		assert.Error(t, err)
+	}
+
+	// 导出到输出目录之外的文件不能记录
+	dir := filepath.Join(root, "out")
+	state, err := core.OpenSyncState(dir)
+	assert.NoError(t, err)
+	assert.Error(t, state.Record("doc", core.SyncRecord{Path: outside}))
+	md := writeTestFile(t, filepath.Join(dir, "Guide.md"), "guide")
+	assert.Error(t, state.Record("doc", core.SyncRecord{Path: md, Assets: []string{outside}}))
+	assert.NoError(t, state.Record("doc", core.SyncRecord{Path: md}))
+	_, err = os.Stat(outside)
+	assert.NoError(t, err)
+}
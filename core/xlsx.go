@@ -0,0 +1,151 @@
+package core
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 生成 XLSX 所需的最小 OpenXML 文件集合，不依赖第三方库
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+%s</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets>
+%s</sheets>
+</workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+%s</Relationships>`
+
+// WriteXLSX 将工作表写入为 XLSX 文件，每个工作表对应一个 sheet
+func WriteXLSX(w io.Writer, sheets []*Sheet) error {
+	writer := zip.NewWriter(w)
+
+	contentTypes := new(strings.Builder)
+	workbookSheets := new(strings.Builder)
+	workbookRels := new(strings.Builder)
+	usedNames := map[string]bool{}
+	for i, sheet := range sheets {
+		id := i + 1
+		contentTypes.WriteString(fmt.Sprintf(
+			`<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`+"\n", id))
+		workbookSheets.WriteString(fmt.Sprintf(
+			`<sheet name="%s" sheetId="%d" r:id="rId%d"/>`+"\n", xmlEscape(xlsxSheetName(sheet.Title, id, usedNames)), id, id))
+		workbookRels.WriteString(fmt.Sprintf(
+			`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`+"\n", id, id))
+	}
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", fmt.Sprintf(xlsxContentTypes, contentTypes.String())},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, workbookSheets.String())},
+		{"xl/_rels/workbook.xml.rels", fmt.Sprintf(xlsxWorkbookRels, workbookRels.String())},
+	}
+	for i, sheet := range sheets {
+		files = append(files, struct {
+			name    string
+			content string
+		}{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), xlsxWorksheet(sheet)})
+	}
+
+	for _, file := range files {
+		f, err := writer.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, file.content); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+func xlsxWorksheet(sheet *Sheet) string {
+	buf := new(strings.Builder)
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	buf.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` + "\n")
+	buf.WriteString("<sheetData>\n")
+	for i, row := range sheet.Rows {
+		if isEmptyRow(row) {
+			continue
+		}
+		buf.WriteString(fmt.Sprintf(`<row r="%d">`, i+1))
+		for j, cell := range row {
+			if cell.Text == "" {
+				continue
+			}
+			ref := fmt.Sprintf("%s%d", SheetColumnName(j), i+1)
+			if cell.IsNumber {
+				buf.WriteString(fmt.Sprintf(`<c r="%s"><v>%s</v></c>`, ref, cell.Text))
+			} else {
+				buf.WriteString(fmt.Sprintf(`<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xmlEscape(cell.Text)))
+			}
+		}
+		buf.WriteString("</row>\n")
+	}
+	buf.WriteString("</sheetData>\n")
+	if len(sheet.Merges) > 0 {
+		buf.WriteString(fmt.Sprintf(`<mergeCells count="%d">`, len(sheet.Merges)))
+		for _, merge := range sheet.Merges {
+			buf.WriteString(fmt.Sprintf(`<mergeCell ref="%s%d:%s%d"/>`,
+				SheetColumnName(merge.Col), merge.Row+1,
+				SheetColumnName(merge.Col+merge.ColSpan-1), merge.Row+merge.RowSpan))
+		}
+		buf.WriteString("</mergeCells>\n")
+	}
+	buf.WriteString("</worksheet>")
+	return buf.String()
+}
+
+// xlsxSheetName 生成合法且不重复的工作表名称：最长 31 个字符，不含 []:*?/\
+// 重名时添加 _<id> 后缀，仍然重名时继续添加序号，直到名称不重复
+func xlsxSheetName(title string, id int, used map[string]bool) string {
+	base := []rune(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, title))
+	for i := 0; ; i++ {
+		suffix := ""
+		if i > 0 {
+			suffix = fmt.Sprintf("_%d", id)
+		}
+		if i > 1 {
+			suffix += fmt.Sprintf("_%d", i)
+		}
+		runes := base
+		if len(runes)+len(suffix) > 31 {
+			runes = runes[:max(31-len(suffix), 0)]
+		}
+		name := string(runes) + suffix
+		if name != "" && !used[strings.ToLower(name)] {
+			used[strings.ToLower(name)] = true
+			return name
+		}
+	}
+}
+
+func xmlEscape(s string) string {
+	buf := new(strings.Builder)
+	xml.EscapeText(buf, []byte(s))
+	return buf.String()
+}
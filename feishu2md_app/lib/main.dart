@@ -194,6 +194,8 @@ Future<void> startBackendService() async {
     final env = Map<String, String>.from(Platform.environment);
     // 确保设置日志相关环境变量
     env['FEISHU2MD_LOG_DIR'] = logDir;
+    // 显式传入访问令牌，不依赖后端写入的令牌文件
+    prepareBackendToken(env);
     
     // 启动后端进程，显式指定工作目录和环境变量
     _backendProcess = await Process.start(
@@ -213,13 +215,18 @@ Future<void> startBackendService() async {
     _backendProcess!.stderr.transform(utf8.decoder).listen((data) {
       print('后端错误: $data');
     });
+
+    _backendProcess!.exitCode.then((code) {
+      print('后端进程已退出: $code');
+      clearBackendToken();
+    });
     
     // 等待后端启动
     await Future.delayed(const Duration(seconds: 2));
     
     // 检查后端是否成功启动
     try {
-      final response = await http.get(Uri.parse('http://localhost:8080/config'));
+      final response = await http.get(Uri.parse('$backendBaseUrl/config'), headers: await backendHeaders());
       if (response.statusCode == 200) {
         print('后端服务已成功启动并响应请求');
       } else {
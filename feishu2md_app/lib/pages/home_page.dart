@@ -59,7 +59,7 @@ class _HomePageState extends State<HomePage> {
     });
     
     try {
-      final response = await http.get(Uri.parse('http://localhost:8080/config'));
+      final response = await http.get(Uri.parse('$backendBaseUrl/config'), headers: await backendHeaders());
       
       if (response.statusCode == 200) {
         final data = json.decode(response.body);
@@ -94,8 +94,8 @@ class _HomePageState extends State<HomePage> {
     
     try {
       final response = await http.post(
-        Uri.parse('http://localhost:8080/config'),
-        headers: {'Content-Type': 'application/json'},
+        Uri.parse('$backendBaseUrl/config'),
+        headers: await backendHeaders(json: true),
         body: json.encode({
           'app_id': _appIdController.text,
           'app_secret': _appSecretController.text,
@@ -179,13 +179,13 @@ class _HomePageState extends State<HomePage> {
       } else {
         // 没有选定空间，通过URL获取空间信息
         final encodedUrl = Uri.encodeComponent(_spaceUrlController.text);
-        final spaceInfoUrl = 'http://localhost:8080/wiki/space-info?url=$encodedUrl';
+        final spaceInfoUrl = '$backendBaseUrl/wiki/space-info?url=$encodedUrl';
         
         setState(() {
           _statusMessage = '正在获取空间信息...';
         });
         
-        final spaceInfoResponse = await http.get(Uri.parse(spaceInfoUrl));
+        final spaceInfoResponse = await http.get(Uri.parse(spaceInfoUrl), headers: await backendHeaders());
         
         if (spaceInfoResponse.statusCode != 200) {
           throw Exception('获取空间信息失败: 服务器返回 ${spaceInfoResponse.statusCode}');
@@ -207,9 +207,9 @@ class _HomePageState extends State<HomePage> {
       
       // 步骤2: 获取顶级节点
       final spaceId = _spaceInfo!['space_id'];
-      final topNodesUrl = 'http://localhost:8080/wiki/top-nodes?space_id=$spaceId';
+      final topNodesUrl = '$backendBaseUrl/wiki/top-nodes?space_id=$spaceId';
       
-      final topNodesResponse = await http.get(Uri.parse(topNodesUrl));
+      final topNodesResponse = await http.get(Uri.parse(topNodesUrl), headers: await backendHeaders());
       
       if (topNodesResponse.statusCode != 200) {
         throw Exception('获取顶级节点失败: 服务器返回 ${topNodesResponse.statusCode}');
@@ -253,10 +253,10 @@ class _HomePageState extends State<HomePage> {
       });
       
       // 步骤4: 保存文档树
-      final saveTreeUrl = 'http://localhost:8080/wiki/save-tree';
+      final saveTreeUrl = '$backendBaseUrl/wiki/save-tree';
       final saveTreeResponse = await http.post(
         Uri.parse(saveTreeUrl),
-        headers: {'Content-Type': 'application/json'},
+        headers: await backendHeaders(json: true),
         body: json.encode({
           'output_path': _outputPathController.text,
           'space_name': _spaceInfo!['space_name'],
@@ -316,7 +316,7 @@ class _HomePageState extends State<HomePage> {
     print('节点详情: ${json.encode(node)}');
     
     // 如果是文档类型，尝试下载文档内容
-    if (node['type'] == 'docx' || node['type'] == 'doc') {
+    if (node['type'] == 'docx' || node['type'] == 'doc' || node['type'] == 'sheet' || node['type'] == 'bitable') {
       try {
         // 获取文档的obj_token
         final objToken = node['obj_token'];
@@ -337,7 +337,7 @@ class _HomePageState extends State<HomePage> {
           print('使用输出目录: $outputDir');
           
           // 调用下载接口，确保所有参数都正确编码
-          final downloadUrl = 'http://localhost:8080/download?token=${Uri.encodeComponent(objToken)}'
+          final downloadUrl = '$backendBaseUrl/download?token=${Uri.encodeComponent(objToken)}'
               '&type=${Uri.encodeComponent(node['type'])}'
               '&output_path=${Uri.encodeComponent(outputDir)}'
               '&path=${Uri.encodeComponent(docPath)}';
@@ -353,7 +353,7 @@ class _HomePageState extends State<HomePage> {
           try {
             final downloadResponse = await client.get(
               Uri.parse(downloadUrl),
-              headers: {'Connection': 'keep-alive'},
+              headers: {...await backendHeaders(), 'Connection': 'keep-alive'},
             ).timeout(const Duration(minutes: 2)); // 增加超时时间
             
             if (downloadResponse.statusCode == 200) {
@@ -398,11 +398,11 @@ class _HomePageState extends State<HomePage> {
       return;
     }
     
-    final childrenUrl = 'http://localhost:8080/wiki/node-children?node_token=$nodeToken';
+    final childrenUrl = '$backendBaseUrl/wiki/node-children?node_token=$nodeToken';
     
     try {
       print('尝试获取节点 ${node['title']} 的子节点，URL: $childrenUrl');
-      final childrenResponse = await http.get(Uri.parse(childrenUrl));
+      final childrenResponse = await http.get(Uri.parse(childrenUrl), headers: await backendHeaders());
       
       if (childrenResponse.statusCode != 200) {
         print('获取子节点失败: 服务器返回 ${childrenResponse.statusCode}，认为节点 ${node['title']} 没有子节点');
@@ -521,7 +521,7 @@ class _HomePageState extends State<HomePage> {
       // 先保存配置
       await _saveConfig();
       
-      final response = await http.get(Uri.parse('http://localhost:8080/wiki/spaces'));
+      final response = await http.get(Uri.parse('$backendBaseUrl/wiki/spaces'), headers: await backendHeaders());
       
       if (response.statusCode != 200) {
         throw Exception('获取空间列表失败: 服务器返回 ${response.statusCode}');
@@ -1,8 +1,65 @@
 import 'dart:convert';
 import 'dart:io';
+import 'dart:math';
 import 'package:path/path.dart' as path;
 import 'package:path_provider/path_provider.dart' as path_provider;
 
+// 后端服务地址，后端默认只监听本机的 127.0.0.1
+const String backendBaseUrl = 'http://127.0.0.1:8080';
+
+String? _backendToken;
+
+// 启动后端时生成的访问令牌，通过环境变量 FEISHU2MD_SERVER_TOKEN 传给后端，
+// 环境中已经设置了令牌时沿用该令牌
+String prepareBackendToken(Map<String, String> env) {
+  var token = env['FEISHU2MD_SERVER_TOKEN'] ?? '';
+  if (token.isEmpty) {
+    final random = Random.secure();
+    token = List.generate(32, (_) => random.nextInt(256).toRadixString(16).padLeft(2, '0')).join();
+    env['FEISHU2MD_SERVER_TOKEN'] = token;
+  }
+  _backendToken = token;
+  return token;
+}
+
+// 启动的后端退出后（例如端口已被其他后端占用）不再使用传入的令牌，改为读取令牌文件
+void clearBackendToken() {
+  _backendToken = null;
+}
+
+// 读取后端保存的访问令牌，后端会将实际使用的令牌写入用户配置目录的 feishu2md/server_token 文件
+// 由本应用启动的后端直接使用启动时传入的令牌
+Future<String?> readBackendToken() async {
+  if (_backendToken != null) return _backendToken;
+  final home = Platform.environment['HOME'] ?? '';
+  final String configDir;
+  if (Platform.isWindows) {
+    configDir = Platform.environment['APPDATA'] ?? '';
+  } else if (Platform.isMacOS) {
+    configDir = path.join(home, 'Library', 'Application Support');
+  } else {
+    configDir = Platform.environment['XDG_CONFIG_HOME'] ?? path.join(home, '.config');
+  }
+  final tokenFile = File(path.join(configDir, 'feishu2md', 'server_token'));
+  if (await tokenFile.exists()) {
+    _backendToken = (await tokenFile.readAsString()).trim();
+  }
+  return _backendToken;
+}
+
+// 请求后端时使用的请求头，包含访问令牌
+Future<Map<String, String>> backendHeaders({bool json = false}) async {
+  final headers = <String, String>{};
+  if (json) {
+    headers['Content-Type'] = 'application/json';
+  }
+  final token = await readBackendToken();
+  if (token != null && token.isNotEmpty) {
+    headers['Authorization'] = 'Bearer $token';
+  }
+  return headers;
+}
+
 // 全局变量跟踪后端进程
 Process? _backendProcess;
 bool _backendStarted = false;
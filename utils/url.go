@@ -3,6 +3,7 @@ package utils
 import (
 	"net/url"
 	"regexp"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -14,8 +15,34 @@ func UnescapeURL(rawURL string) string {
 	return rawURL
 }
 
+// 已知的公有云域名及对应的开放平台接口地址
+var knownDomains = []struct {
+	Domain  string
+	BaseURL string
+}{
+	{"feishu.cn", "https://open.feishu.cn"},
+	{"larksuite.com", "https://open.larksuite.com"},
+	{"larkoffice.com", "https://open.feishu.cn"},
+}
+
+// ParseDomain 根据文档链接的域名得到文档域名和开放平台接口地址
+// 私有化部署的域名无法推断接口地址，此时 baseURL 为空，由配置决定
+func ParseDomain(rawURL string) (domain, baseURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return "", ""
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, known := range knownDomains {
+		if host == known.Domain || strings.HasSuffix(host, "."+known.Domain) {
+			return known.Domain, known.BaseURL
+		}
+	}
+	return host, ""
+}
+
 func ValidateDocumentURL(url string) (string, string, error) {
-	reg := regexp.MustCompile("^https://[\\w-.]+/(docs|docx|wiki)/([a-zA-Z0-9]+)")
+	reg := regexp.MustCompile("^https://[\\w-.]+/(docs|docx|wiki|sheets|base)/([a-zA-Z0-9]+)")
 	matchResult := reg.FindStringSubmatch(url)
 	if matchResult == nil || len(matchResult) != 3 {
 		return "", "", errors.Errorf("Invalid feishu/larksuite document URL pattern")
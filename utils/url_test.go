@@ -61,6 +61,16 @@ func TestValidateDownloadURL(t *testing.T) {
 			url:   "https://sample.f.mioffice.cn/docx/doccnByZP6puODElAYySJkPIfUb",
 			noErr: true,
 		},
+		{
+			name:  "validate feishu sheets url success",
+			url:   "https://sample.feishu.cn/sheets/shtcnByZP6puODElAYySJkPIfUb",
+			noErr: true,
+		},
+		{
+			name:  "validate feishu base url success",
+			url:   "https://sample.feishu.cn/base/bascnByZP6puODElAYySJkPIfUb",
+			noErr: true,
+		},
 		{
 			name:  "validate arbitrary url failed",
 			url:   "https://google.com",
@@ -121,3 +131,43 @@ func TestValidWikiURL(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDomain(t *testing.T) {
+	tests := []struct {
+		name        string
+		rawURL      string
+		wantDomain  string
+		wantBaseURL string
+	}{
+		{
+			name:        "feishu",
+			rawURL:      "https://sample.feishu.cn/docx/doxcnXhd93zqoLnmVPGIPTy7AFe",
+			wantDomain:  "feishu.cn",
+			wantBaseURL: "https://open.feishu.cn",
+		},
+		{
+			name:        "larksuite",
+			rawURL:      "https://sample.sg.larksuite.com/wiki/wikcnLgRX9AMtvaB5x1cl57Yuah",
+			wantDomain:  "larksuite.com",
+			wantBaseURL: "https://open.larksuite.com",
+		},
+		{
+			name:        "private deployment",
+			rawURL:      "https://docs.example.com/docx/doxcnXhd93zqoLnmVPGIPTy7AFe",
+			wantDomain:  "docs.example.com",
+			wantBaseURL: "",
+		},
+		{
+			name:   "invalid url",
+			rawURL: "not a url",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain, baseURL := ParseDomain(tt.rawURL)
+			if domain != tt.wantDomain || baseURL != tt.wantBaseURL {
+				t.Errorf("URL = %v\nGot = %v, %v\nExpected = %v, %v", tt.rawURL, domain, baseURL, tt.wantDomain, tt.wantBaseURL)
+			}
+		})
+	}
+}
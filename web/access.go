@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/gin-gonic/gin"
+)
+
+// 访问令牌的环境变量，设置后不读取和生成配置目录中的令牌文件
+const serverTokenEnv = "FEISHU2MD_SERVER_TOKEN"
+
+// 访问令牌文件与配置文件位于同一目录；桌面客户端从默认配置目录中的同名文件读取令牌
+const serverTokenFile = "server_token"
+
+// 默认只监听本机地址，局域网中的其他主机和网页无法直接访问
+const defaultServerHost = "127.0.0.1"
+
+// 读取访问令牌，首次启动时生成随机令牌并保存到只有当前用户可以读写的文件中
+func loadServerToken(configPath string) (token, tokenPath string, err error) {
+	if token := os.Getenv(serverTokenEnv); token != "" {
+		return token, "", nil
+	}
+	tokenPath = filepath.Join(filepath.Dir(configPath), serverTokenFile)
+	data, err := os.ReadFile(tokenPath)
+	if err == nil && strings.TrimSpace(string(data)) != "" {
+		return strings.TrimSpace(string(data)), tokenPath, nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", "", err
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(buf)
+	if err := os.MkdirAll(filepath.Dir(tokenPath), 0o700); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(tokenPath, []byte(token), 0o600); err != nil {
+		return "", "", err
+	}
+	return token, tokenPath, nil
+}
+
+// 将实际使用的访问令牌写入桌面客户端读取的文件，使用环境变量或 -config 指定其他配置文件时
+// 客户端仍然可以读取到当前的令牌，返回写入的文件路径，与 tokenPath 相同时不需要写入
+func publishServerToken(token, tokenPath string) (string, error) {
+	configPath, err := core.GetConfigFilePath()
+	if err != nil {
+		return "", err
+	}
+	clientPath := filepath.Join(filepath.Dir(configPath), serverTokenFile)
+	if tokenPath != "" && sameFile(clientPath, tokenPath) {
+		return "", nil
+	}
+	if data, err := os.ReadFile(clientPath); err == nil && strings.TrimSpace(string(data)) == token {
+		return "", nil
+	}
+	if err := os.MkdirAll(filepath.Dir(clientPath), 0o700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(clientPath, []byte(token), 0o600); err != nil {
+		return "", err
+	}
+	// 文件已存在时 WriteFile 不会修改权限
+	return clientPath, os.Chmod(clientPath, 0o600)
+}
+
+func sameFile(a, b string) bool {
+	a, errA := filepath.Abs(a)
+	b, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && a == b
+}
+
+// 校验请求头中的访问令牌，支持 Authorization: Bearer <令牌> 和 X-API-Key: <令牌>
+func tokenAuth(token string) gin.HandlerFunc {
+	return checkToken(token, false)
+}
+
+// 浏览器跳转无法设置请求头，除请求头外也接受查询参数 token
+func queryTokenAuth(token string) gin.HandlerFunc {
+	return checkToken(token, true)
+}
+
+func checkToken(token string, allowQuery bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+		provided := c.GetHeader("X-API-Key")
+		if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			provided = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if provided == "" && allowQuery {
+			provided = c.Query("token")
+		}
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"success": false, "message": "缺少或无效的访问令牌"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// 只允许同源请求和来源在白名单中的跨域请求，防止其他网页借用本机的应用凭证
+func corsMiddleware(allowedOrigins []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || sameOrigin(origin, c.Request.Host) {
+			c.Next()
+			return
+		}
+		if !originAllowed(origin, allowedOrigins) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"success": false, "message": fmt.Sprintf("不允许来自 %s 的跨域请求", origin)})
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Add("Vary", "Origin")
+		header.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		header.Set("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-API-Key")
+		header.Set("Access-Control-Expose-Headers", "Content-Disposition")
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
+
+func sameOrigin(origin, host string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, host)
+}
+
+func originAllowed(origin string, allowedOrigins []string) bool {
+	origin = strings.TrimSuffix(origin, "/")
+	for _, allowed := range allowedOrigins {
+		allowed = strings.TrimSuffix(strings.TrimSpace(allowed), "/")
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// 判断监听地址是否只能从本机访问
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// 授权回调使用的服务地址，启动时根据配置的公开地址或监听地址设置，不使用请求中的 Host
+var publicBaseURL = "http://localhost:8080"
+
+// 返回浏览器访问服务的地址，未配置公开地址时使用监听地址，监听所有地址或本机地址时使用 localhost
+func serverBaseURL(publicURL, scheme, host string, port int) string {
+	if publicURL != "" {
+		return strings.TrimSuffix(publicURL, "/")
+	}
+	if ip := net.ParseIP(host); host == "" || isLoopbackHost(host) || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, strconv.Itoa(port)))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishServerToken(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+	clientPath := filepath.Join(home, "feishu2md", serverTokenFile)
+	if dir, err := os.UserConfigDir(); err != nil || dir != home {
+		t.Skip("当前平台的配置目录不受环境变量控制")
+	}
+
+	// 使用 -config 指定的配置目录时，客户端读取的文件中写入实际使用的令牌
+	token, tokenPath, err := loadServerToken(filepath.Join(t.TempDir(), "config.json"))
+	assert.NoError(t, err)
+	path, err := publishServerToken(token, tokenPath)
+	assert.NoError(t, err)
+	assert.Equal(t, clientPath, path)
+	data, err := os.ReadFile(clientPath)
+	assert.NoError(t, err)
+	assert.Equal(t, token, string(data))
+
+	// 环境变量指定的令牌替换旧的令牌文件
+	t.Setenv(serverTokenEnv, "env-token")
+	token, tokenPath, err = loadServerToken(filepath.Join(home, "feishu2md", "config.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, "", tokenPath)
+	_, err = publishServerToken(token, tokenPath)
+	assert.NoError(t, err)
+	data, err = os.ReadFile(clientPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "env-token", string(data))
+	info, err := os.Stat(clientPath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// 令牌文件就是客户端读取的文件时不需要再次写入
+	t.Setenv(serverTokenEnv, "")
+	token, tokenPath, err = loadServerToken(filepath.Join(home, "feishu2md", "config.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, "env-token", token)
+	path, err = publishServerToken(token, tokenPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "", path)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 导出文档到临时目录，打包为 ZIP 文件返回给浏览器下载，不在服务器上保留文件
+// 参数与 /download 相同，图片总是保存在 ZIP 中或上传到对象存储，不使用共享图片库
+func downloadZipHandler(c *gin.Context) {
+	docURL, err := url.QueryUnescape(c.Query("url"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的文档URL"})
+		return
+	}
+	docType, docToken, err := documentParams(c, docURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": fmt.Sprintf("无效的文档URL: %s", err)})
+		return
+	}
+
+	ctx := context.Background()
+	config, ok := requestConfig(c, docURL)
+	if !ok {
+		return
+	}
+	client := newClient(config, docURL)
+	nodeToken := ""
+	if docType == "wiki" {
+		nodeToken = docToken
+	}
+	docType, docToken, _, err = resolveWikiDocument(ctx, client, docType, docToken)
+	if err != nil {
+		respondAPIError(c, "获取知识库节点信息失败", err)
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "feishu2md-")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": fmt.Sprintf("创建临时目录失败: %s", err)})
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	// 临时目录由这次请求创建，其中只有本次导出的文件，不需要同步状态判断文件归属
+	target := exportTarget{Dir: dir, NodeToken: nodeToken, Namer: newFileNamer(config.Output.Filename, nil)}
+	var exported string
+	switch docType {
+	case "docx":
+		imageOpts, err := imageOptions(c, config)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": fmt.Sprintf("图片处理参数无效: %s", err)})
+			return
+		}
+		var result *docxExportResult
+		result, err = exportDocx(ctx, client, config, nil, docToken, docxExportOptions{
+			Dir:          dir,
+			NodeToken:    nodeToken,
+			Namer:        target.Namer,
+			Concurrency:  assetConcurrency(c, config),
+			Image:        imageOpts,
+			SinkType:     c.DefaultQuery("asset_sink", config.Output.AssetSink.Type),
+			DocumentJSON: c.DefaultQuery("document_json", strconv.FormatBool(config.Output.DocumentJSON)) == "true",
+		})
+		if err == nil {
+			exported = result.Path
+		}
+	case "sheet", "sheets":
+		var filePaths []string
+		filePaths, err = exportSheet(ctx, client, docToken, target, c.DefaultQuery("sheet_format", config.Output.SheetFormat))
+		if err == nil {
+			exported = filePaths[0]
+		}
+	case "bitable", "base":
+		exported, _, err = exportBitable(ctx, client, docToken, target, assetConcurrency(c, config))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": fmt.Sprintf("不支持的文档类型: %s", docType)})
+		return
+	}
+	if err != nil {
+		log.Printf("导出文档失败: %s", err)
+		respondAPIError(c, "导出文档失败", err)
+		return
+	}
+
+	// 边打包边发送，不在内存中保存整个 ZIP 文件；开始发送后出错只能中断连接
+	name := strings.TrimSuffix(filepath.Base(exported), filepath.Ext(exported)) + ".zip"
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(name)))
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+	if err := writeZip(c.Writer, dir); err != nil {
+		log.Printf("打包ZIP文件失败: %s", err)
+		c.Abort()
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// 将目录中的所有文件写入 ZIP，条目路径相对于目录
+func writeZip(w io.Writer, dir string) error {
+	writer := zip.NewWriter(w)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f, err := writer.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(f, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteZip(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Guide.md":              "# Guide",
+		"Guide_assets/img.png":  "png",
+		"Guide_assets/a/b.json": "{}",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, writeZip(buf, dir))
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	got := map[string]string{}
+	for _, file := range reader.File {
+		f, err := file.Open()
+		assert.NoError(t, err)
+		data, err := io.ReadAll(f)
+		assert.NoError(t, err)
+		f.Close()
+		got[file.Name] = string(data)
+	}
+	assert.Equal(t, files, got)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/gin-gonic/gin"
+)
+
+// 将文档中的图片保存到共享图片库，Markdown 中的图片改为相对于 mdDir 的链接
+// 新下载的图片按照 imageOpts 处理后再保存，图片库中已有的图片不会重新处理
+// 更新文档引用的图片后，清理不再被任何文档引用的图片
+func storeImages(ctx context.Context, client *core.Client, storeDir, docToken, mdDir, markdown string, tokens []string, concurrency int, imageOpts core.ImageOptions) (string, []gin.H, error) {
+	store, err := core.OpenAssetStore(storeDir)
+	if err != nil {
+		return markdown, nil, err
+	}
+
+	var missing []string
+	for _, token := range tokens {
+		if _, ok := store.Lookup(token); !ok {
+			missing = append(missing, token)
+		}
+	}
+	log.Printf("文档共 %d 个图片，共享图片库中缺少 %d 个", len(tokens), len(missing))
+
+	var assetErrors []gin.H
+	for _, asset := range client.DownloadAssets(ctx, missing, store.Dir, concurrency) {
+		if asset.Err == nil {
+			if err := asset.ProcessImage(imageOpts); err != nil {
+				log.Printf("处理图片 %s 失败: %s", asset.Token, err)
+			}
+			_, asset.Err = store.Put(asset.Token, filepath.Ext(asset.Path), asset.Data)
+		}
+		if asset.Err != nil {
+			log.Printf("保存图片 %s 失败: %s", asset.Token, asset.Err)
+			assetErrors = append(assetErrors, gin.H{"token": asset.Token, "error": asset.Err.Error()})
+		}
+	}
+
+	var names []string
+	for _, token := range tokens {
+		name, ok := store.Lookup(token)
+		if !ok {
+			continue
+		}
+		link, err := store.RelativePath(mdDir, name)
+		if err != nil {
+			return markdown, assetErrors, err
+		}
+		names = append(names, name)
+		markdown = strings.ReplaceAll(markdown, token, link)
+	}
+
+	if err := store.UpdateDocument(docToken, names); err != nil {
+		return markdown, assetErrors, err
+	}
+	removed, err := store.GC()
+	if err != nil {
+		return markdown, assetErrors, err
+	}
+	if len(removed) > 0 {
+		log.Printf("清理了 %d 个不再被引用的图片", len(removed))
+	}
+	return markdown, assetErrors, nil
+}
+
+// 按照 asset_sink 参数选择图片的保存位置
+// 本地保存时使用文档专属的图片目录，上传到对象存储时以文档 token 作为对象键的前缀
+func newAssetSink(sinkType string, config core.AssetSinkConfig, outputPath, docToken, docTitle string) (core.AssetSink, error) {
+	switch sinkType {
+	case "", core.AssetSinkLocal:
+		docImagesDir := filepath.Join(outputPath, docTitle+"_images")
+		log.Printf("为文档创建专属图片目录: %s", docImagesDir)
+		return core.NewLocalSink(docImagesDir, filepath.Base(docImagesDir))
+	case core.AssetSinkS3:
+		config.Prefix = path.Join(config.Prefix, docToken)
+		log.Printf("图片上传到对象存储: %s/%s", config.Bucket, config.Prefix)
+		return core.NewS3Sink(config)
+	default:
+		return nil, fmt.Errorf("不支持的图片保存位置: %s", sinkType)
+	}
+}
+
+// 处理下载的图片并保存到 sink，Markdown 中的图片 token 替换为 sink 返回的链接，同时返回保存成功的文件名
+// 单个图片失败不影响其他图片，失败的图片记录在返回的错误列表中
+func sinkImages(ctx context.Context, sink core.AssetSink, markdown string, assets []*core.AssetResult, imageOpts core.ImageOptions) (string, []string, []gin.H) {
+	var names []string
+	var assetErrors []gin.H
+	for i, asset := range assets {
+		log.Printf("处理图片 %d/%d: token=%s", i+1, len(assets), asset.Token)
+		if asset.Err != nil {
+			log.Printf("下载图片失败: %s", asset.Err)
+			assetErrors = append(assetErrors, gin.H{"token": asset.Token, "error": asset.Err.Error()})
+			continue
+		}
+		// 处理失败时保留原图
+		if err := asset.ProcessImage(imageOpts); err != nil {
+			log.Printf("处理图片失败: %s", err)
+		}
+		name := path.Base(asset.Path)
+		link, err := sink.Put(ctx, name, asset.Data)
+		if err != nil {
+			log.Printf("保存图片失败: %s", err)
+			assetErrors = append(assetErrors, gin.H{"token": asset.Token, "error": err.Error()})
+			continue
+		}
+		// 同一图片出现多次时全部替换
+		log.Printf("图片保存成功，在Markdown中使用链接: %s", link)
+		markdown = strings.ReplaceAll(markdown, asset.Token, link)
+		names = append(names, name)
+	}
+	return markdown, names, assetErrors
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/gin-gonic/gin"
+)
+
+// 等待回调的授权请求，state 用于防止跨站请求伪造
+var pendingStates = struct {
+	sync.Mutex
+	states map[string]oauthState
+}{states: map[string]oauthState{}}
+
+// 授权请求的创建时间和发起登录时使用的命名配置
+type oauthState struct {
+	createdAt time.Time
+	profile   string
+}
+
+// 授权请求的有效时间
+const oauthStateTTL = 10 * time.Minute
+
+func newOAuthState(profile string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	state := hex.EncodeToString(buf)
+
+	pendingStates.Lock()
+	defer pendingStates.Unlock()
+	now := time.Now()
+	for s, pending := range pendingStates.states {
+		if now.Sub(pending.createdAt) > oauthStateTTL {
+			delete(pendingStates.states, s)
+		}
+	}
+	pendingStates.states[state] = oauthState{createdAt: now, profile: profile}
+	return state, nil
+}
+
+// 校验并删除授权请求，返回发起登录时使用的命名配置
+func consumeOAuthState(state string) (string, bool) {
+	pendingStates.Lock()
+	defer pendingStates.Unlock()
+	pending, ok := pendingStates.states[state]
+	delete(pendingStates.states, state)
+	return pending.profile, ok && time.Since(pending.createdAt) <= oauthStateTTL
+}
+
+// 获取用户令牌存储，每个命名配置使用单独的令牌文件
+func userTokenStore(profile string) (core.UserTokenStore, error) {
+	tokenPath, err := core.GetUserTokenFilePath()
+	if err != nil {
+		return nil, err
+	}
+	if profile != "" {
+		tokenPath = strings.TrimSuffix(tokenPath, ".json") + "." + profile + ".json"
+	}
+	return core.NewFileTokenStore(tokenPath), nil
+}
+
+// 跳转到飞书授权页面，授权后回调 /auth/callback，需要访问令牌，浏览器打开时可以使用查询参数 token
+func loginHandler(c *gin.Context) {
+	config, ok := requestConfig(c, "")
+	if !ok {
+		return
+	}
+	client := newClient(config, "")
+	state, err := newOAuthState(config.ProfileName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("生成授权请求失败: %s", err),
+		})
+		return
+	}
+	redirectURI := publicBaseURL + "/auth/callback"
+	c.Redirect(http.StatusFound, client.AuthorizeURL(c.Request.Context(), redirectURI, state))
+}
+
+// 授权回调，使用授权码换取并保存用户令牌
+func callbackHandler(c *gin.Context) {
+	profile, ok := consumeOAuthState(c.Query("state"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效或已过期的授权请求，请重新登录",
+		})
+		return
+	}
+	code := c.Query("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "缺少授权码",
+		})
+		return
+	}
+
+	config, err := loadProfileConfig(profile, "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	client := newClient(config, "")
+	token, err := client.ExchangeCode(c.Request.Context(), code)
+	if err != nil {
+		log.Printf("用户授权失败: %s", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "用户授权失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	log.Printf("用户登录成功: %s", token.Name)
+	c.Data(http.StatusOK, "text/html; charset=utf-8",
+		[]byte(fmt.Sprintf("<p>%s 登录成功，可以关闭此页面。</p>", html.EscapeString(token.Name))))
+}
+
+// 查询当前登录的用户
+func authStatusHandler(c *gin.Context) {
+	config, ok := requestConfig(c, "")
+	if !ok {
+		return
+	}
+	client := newClient(config, "")
+	user, err := client.CurrentUser()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("读取用户令牌失败: %s", err),
+		})
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{"success": true, "logged_in": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":            true,
+		"logged_in":          time.Now().Before(user.RefreshExpiresAt),
+		"name":               user.Name,
+		"open_id":            user.OpenID,
+		"refresh_expires_at": user.RefreshExpiresAt,
+	})
+}
+
+// 退出登录，之后使用应用身份访问文档
+func logoutHandler(c *gin.Context) {
+	config, ok := requestConfig(c, "")
+	if !ok {
+		return
+	}
+	client := newClient(config, "")
+	if err := client.Logout(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("删除用户令牌失败: %s", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "已退出登录"})
+}
+
+// 命令行登录：在本地端口等待授权回调，保存用户令牌后退出
+func runLogin(port int) error {
+	config, err := loadProfileConfig("", "")
+	if err != nil {
+		return err
+	}
+	client := newClient(config, "")
+	state, err := newOAuthState(config.ProfileName)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+	if err != nil {
+		return fmt.Errorf("监听端口 %d 失败: %w", port, err)
+	}
+	redirectURI := fmt.Sprintf("http://localhost:%d/auth/callback", port)
+
+	result := make(chan error, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auth/callback", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := consumeOAuthState(r.URL.Query().Get("state")); !ok {
+			http.Error(w, "无效或已过期的授权请求", http.StatusBadRequest)
+			return
+		}
+		token, err := client.ExchangeCode(r.Context(), r.URL.Query().Get("code"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			result <- err
+			return
+		}
+		fmt.Fprintf(w, "%s 登录成功，可以关闭此页面。", token.Name)
+		result <- nil
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	fmt.Printf("请在浏览器中打开以下链接完成授权（回调地址 %s 需要在开发者后台的安全设置中添加）:\n\n%s\n\n",
+		redirectURI, client.AuthorizeURL(context.Background(), redirectURI, state))
+
+	select {
+	case err := <-result:
+		if err == nil {
+			fmt.Println("登录成功，用户令牌已保存")
+		}
+		return err
+	case <-time.After(oauthStateTTL):
+		return fmt.Errorf("等待授权超时")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallbackHandlerEscapesName(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/app_access_token/internal") {
+			fmt.Fprint(w, `{"code":0,"msg":"ok","app_access_token":"a-1","expire":7200}`)
+			return
+		}
+		fmt.Fprint(w, `{"code":0,"msg":"ok","data":{"access_token":"u-1","refresh_token":"ur-1","expires_in":7200,"refresh_expires_in":86400,"name":"<script>alert(1)</script>"}}`)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	assert.NoError(t, initConfig(filepath.Join(dir, "config.json"), configOverrides{
+		AppID:      "callback-test",
+		AppSecret:  "secret",
+		BaseURL:    server.URL,
+		OutputPath: filepath.Join(dir, "output"),
+	}))
+	router := setupRouter("", "test-token", nil)
+
+	state, err := newOAuthState("")
+	assert.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/auth/callback?code=c&state="+state, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// 用户名称中的 HTML 被转义，不会在回调页面中执行
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "<script>")
+	assert.Contains(t, w.Body.String(), "&lt;script&gt;alert(1)&lt;/script&gt;")
+}
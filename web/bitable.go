@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/gin-gonic/gin"
+)
+
+// 导出多维表格：每张数据表导出为 CSV 和 NDJSON，并生成描述字段类型的 schema.json
+func downloadBitable(c *gin.Context, ctx context.Context, client *core.Client, token string, target exportTarget, concurrency int) {
+	bitableDir, filePaths, err := exportBitable(ctx, client, token, target, concurrency)
+	if err != nil {
+		log.Printf("导出多维表格失败: %s", err)
+		respondAPIError(c, "导出多维表格失败", err)
+		return
+	}
+
+	log.Printf("多维表格导出成功: %s", bitableDir)
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "多维表格导出成功",
+		"file_path":  bitableDir,
+		"file_paths": filePaths,
+	})
+}
+
+// 多维表格目录中的表结构文件，第一个写入，同步状态按照它判断目录属于哪个多维表格
+const bitableSchemaFile = "schema.json"
+
+// 获取多维表格并写入以名称命名的目录，返回目录和生成的文件路径
+func exportBitable(ctx context.Context, client *core.Client, token string, target exportTarget, concurrency int) (string, []string, error) {
+	log.Printf("开始获取多维表格内容: token=%s", token)
+	bitable, err := client.GetBitable(ctx, token)
+	if err != nil {
+		return "", nil, err
+	}
+	log.Printf("成功获取多维表格内容: 名称=%s, 数据表数量=%d", bitable.Name, len(bitable.Tables))
+
+	bitableDir := target.directory(bitableSchemaFile, bitable.Name, token)
+	filePaths, err := writeBitable(ctx, client, bitable, bitableDir, target.Namer.options, concurrency)
+	if err != nil {
+		return "", nil, fmt.Errorf("保存多维表格失败: %w", err)
+	}
+	target.record(token, bitable.Name, filePaths)
+	return bitableDir, filePaths, nil
+}
+
+func writeBitable(ctx context.Context, client *core.Client, bitable *core.Bitable, bitableDir string, options core.FilenameOptions, concurrency int) ([]string, error) {
+	// 数据表名称可能重复，重名的数据表按照顺序添加后缀
+	namer := newFileNamer(options, nil)
+	assetsDir := filepath.Join(bitableDir, "assets")
+	if err := os.MkdirAll(bitableDir, 0755); err != nil {
+		return nil, err
+	}
+
+	var filePaths []string
+	schema, err := json.MarshalIndent(bitable.Schema(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	schemaPath := filepath.Join(bitableDir, bitableSchemaFile)
+	if err := os.WriteFile(schemaPath, schema, 0644); err != nil {
+		return nil, err
+	}
+	filePaths = append(filePaths, schemaPath)
+
+	for _, table := range bitable.Tables {
+		// 下载附件，失败时保留附件名称并继续处理其他附件
+		attachments := table.Attachments()
+		names := map[string]string{}
+		tokens := make([]string, 0, len(attachments))
+		for _, attachment := range attachments {
+			names[attachment.FileToken] = attachment.Name
+			tokens = append(tokens, attachment.FileToken)
+		}
+		assetPaths := map[string]string{}
+		for _, asset := range client.DownloadAssets(ctx, tokens, assetsDir, concurrency) {
+			if asset.Err != nil {
+				log.Printf("下载附件 %s 失败: %s", names[asset.Token], asset.Err)
+				continue
+			}
+			if err := os.MkdirAll(assetsDir, 0755); err != nil {
+				return nil, err
+			}
+			if err := os.WriteFile(asset.Path, asset.Data, 0644); err != nil {
+				log.Printf("保存附件 %s 失败: %s", names[asset.Token], err)
+				continue
+			}
+			assetPaths[asset.Token] = "assets/" + filepath.Base(asset.Path)
+		}
+
+		base := namer.dir(bitableDir, table.Name, table.TableID)
+		content, err := table.ToCSV(assetPaths)
+		if err != nil {
+			return nil, err
+		}
+		csvPath := base + ".csv"
+		if err := os.WriteFile(csvPath, []byte(content), 0644); err != nil {
+			return nil, err
+		}
+
+		content, err = table.ToNDJSON()
+		if err != nil {
+			return nil, err
+		}
+		ndjsonPath := base + ".ndjson"
+		if err := os.WriteFile(ndjsonPath, []byte(content), 0644); err != nil {
+			return nil, err
+		}
+		filePaths = append(filePaths, csvPath, ndjsonPath)
+	}
+	return filePaths, nil
+}
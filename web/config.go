@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/gin-gonic/gin"
+)
+
+// 未在请求参数和配置中指定输出目录时使用的默认目录
+const defaultOutputPath = "output"
+
+// 配置接口使用的结构，应用凭证和输出目录保持扁平的字段，兼容旧版客户端
+type ConfigInfo struct {
+	AppID      string `json:"app_id"`
+	AppSecret  string `json:"app_secret"`
+	OutputPath string `json:"output_path"`
+	BaseURL    string `json:"base_url"` // 开放平台接口地址，国际版或私有化部署时填写
+	Domain     string `json:"domain"`   // 文档链接的域名
+	// Output 完整的导出选项，为空时只更新输出目录
+	Output *core.OutputConfig `json:"output,omitempty"`
+}
+
+// 环境变量和命令行参数指定的配置，优先于配置文件，不会写入配置文件
+type configOverrides struct {
+	AppID      string
+	AppSecret  string
+	BaseURL    string
+	Domain     string
+	OutputPath string
+	// Profile 默认使用的命名配置，为空时根据文档链接选择
+	Profile string
+	// OutputRoots 接口允许写入的目录
+	OutputRoots []string
+	// ImportRoots 导入接口允许读取的目录
+	ImportRoots []string
+}
+
+// 读取环境变量中的配置
+func envOverrides() configOverrides {
+	return configOverrides{
+		AppID:      os.Getenv("FEISHU_APP_ID"),
+		AppSecret:  os.Getenv("FEISHU_APP_SECRET"),
+		BaseURL:    os.Getenv("FEISHU_BASE_URL"),
+		Domain:     os.Getenv("FEISHU_DOMAIN"),
+		OutputPath: os.Getenv("FEISHU_OUTPUT_PATH"),
+		Profile:    os.Getenv("FEISHU_PROFILE"),
+	}
+}
+
+// 用 other 中不为空的字段覆盖当前的值
+func (o configOverrides) merge(other configOverrides) configOverrides {
+	merged := configOverrides{
+		AppID:       firstNonEmpty(other.AppID, o.AppID),
+		AppSecret:   firstNonEmpty(other.AppSecret, o.AppSecret),
+		BaseURL:     firstNonEmpty(other.BaseURL, o.BaseURL),
+		Domain:      firstNonEmpty(other.Domain, o.Domain),
+		OutputPath:  firstNonEmpty(other.OutputPath, o.OutputPath),
+		Profile:     firstNonEmpty(other.Profile, o.Profile),
+		OutputRoots: o.OutputRoots,
+		ImportRoots: o.ImportRoots,
+	}
+	if len(other.OutputRoots) > 0 {
+		merged.OutputRoots = other.OutputRoots
+	}
+	if len(other.ImportRoots) > 0 {
+		merged.ImportRoots = other.ImportRoots
+	}
+	return merged
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// 将环境变量和命令行参数应用到配置
+func (o configOverrides) apply(config *core.Config) {
+	config.Feishu.AppId = firstNonEmpty(o.AppID, config.Feishu.AppId)
+	config.Feishu.AppSecret = firstNonEmpty(o.AppSecret, config.Feishu.AppSecret)
+	config.Feishu.BaseURL = firstNonEmpty(o.BaseURL, config.Feishu.BaseURL)
+	config.Feishu.Domain = firstNonEmpty(o.Domain, config.Feishu.Domain)
+	config.Output.OutputPath = firstNonEmpty(o.OutputPath, config.Output.OutputPath)
+	if len(o.OutputRoots) > 0 {
+		config.Server.OutputRoots = o.OutputRoots
+	}
+	if len(o.ImportRoots) > 0 {
+		config.Server.ImportRoots = o.ImportRoots
+	}
+}
+
+// 服务的配置，应用凭证只保存在内存和配置文件中，不写入进程的环境变量
+type configStore struct {
+	mu        sync.RWMutex
+	path      string
+	config    *core.Config
+	overrides configOverrides
+	// roots 启动时按照命令行参数和配置文件确定的允许写入的目录，不随 /config 保存的配置改变
+	roots []string
+}
+
+var serverConfig = &configStore{config: core.NewConfig("", "")}
+
+// 读取配置文件，path 为空时使用 core.GetConfigFilePath 的默认位置，文件不存在时使用默认配置
+func initConfig(path string, overrides configOverrides) error {
+	if path == "" {
+		var err error
+		if path, err = core.GetConfigFilePath(); err != nil {
+			return fmt.Errorf("获取配置文件路径失败: %w", err)
+		}
+	}
+	config, err := readServerConfig(path)
+	if errors.Is(err, os.ErrNotExist) {
+		config, err = core.NewConfig("", ""), nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取配置文件 %s 失败: %w", path, err)
+	}
+
+	serverConfig.mu.Lock()
+	serverConfig.path = path
+	serverConfig.config = config
+	serverConfig.overrides = overrides
+	serverConfig.mu.Unlock()
+
+	roots := defaultOutputRoots()
+	serverConfig.mu.Lock()
+	serverConfig.roots = roots
+	serverConfig.mu.Unlock()
+	log.Printf("配置文件: %s", path)
+	return nil
+}
+
+// 读取配置文件，兼容旧版服务保存的扁平格式
+func readServerConfig(path string) (*core.Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if _, ok := fields["feishu"]; ok || fields["app_id"] == nil {
+		return core.ReadConfigFromFile(path)
+	}
+
+	var legacy ConfigInfo
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return nil, err
+	}
+	config := core.NewConfig("", "")
+	legacy.applyTo(config)
+	log.Printf("已读取旧版格式的配置文件，保存配置时将转换为新格式")
+	return config, nil
+}
+
+// 使用环境变量或口令命令提供的口令加密配置文件中的明文密钥和已保存的用户令牌，用于迁移旧的配置文件
+func runEncryptConfig() error {
+	passphrase, err := core.LookupPassphrase()
+	if err != nil {
+		return err
+	}
+	if passphrase == "" {
+		return fmt.Errorf("请通过环境变量 %s 或 %s 提供加密口令", core.PassphraseEnv, core.PassphraseCommandEnv)
+	}
+
+	serverConfig.mu.Lock()
+	defer serverConfig.mu.Unlock()
+	if _, err := os.Stat(serverConfig.path); err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	if err := serverConfig.config.WriteConfig2File(serverConfig.path); err != nil {
+		return fmt.Errorf("写入配置文件失败: %w", err)
+	}
+	log.Printf("已加密配置文件中的密钥: %s", serverConfig.path)
+
+	// 已保存的用户令牌重新写入后同样加密
+	for _, profile := range append([]string{""}, serverConfig.config.ProfileNames()...) {
+		store, err := userTokenStore(profile)
+		if err != nil {
+			return err
+		}
+		token, err := store.Load()
+		if err != nil {
+			return fmt.Errorf("读取用户令牌 %s 失败: %w", store, err)
+		}
+		if token == nil {
+			continue
+		}
+		if err := store.Save(token); err != nil {
+			return fmt.Errorf("写入用户令牌 %s 失败: %w", store, err)
+		}
+		log.Printf("已加密用户令牌: %s", store)
+	}
+	return nil
+}
+
+// 返回应用了环境变量和命令行参数的配置副本
+func loadConfig() *core.Config {
+	serverConfig.mu.RLock()
+	defer serverConfig.mu.RUnlock()
+	config := *serverConfig.config
+	serverConfig.overrides.apply(&config)
+	return &config
+}
+
+// 返回请求使用的配置，name 为空时使用 -profile 指定的命名配置，仍为空时根据文档链接选择
+// 环境变量和命令行参数中的凭证只覆盖全局配置，输出目录对所有命名配置都生效
+func loadProfileConfig(name, rawURL string) (*core.Config, error) {
+	serverConfig.mu.RLock()
+	overrides := serverConfig.overrides
+	serverConfig.mu.RUnlock()
+
+	config, err := loadConfig().SelectProfile(firstNonEmpty(name, overrides.Profile), rawURL)
+	if err != nil {
+		return nil, err
+	}
+	config.Output.OutputPath = firstNonEmpty(overrides.OutputPath, config.Output.OutputPath)
+	return config, nil
+}
+
+// 按照请求参数 profile 和文档链接选择配置，配置不存在时返回 400
+func requestConfig(c *gin.Context, rawURL string) (*core.Config, bool) {
+	config, err := loadProfileConfig(c.Query("profile"), rawURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return nil, false
+	}
+	return config, true
+}
+
+// 更新配置并写入配置文件
+func (s *configStore) update(info ConfigInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	config := *s.config
+	info.applyTo(&config)
+	if s.path == "" {
+		return errors.New("未设置配置文件路径")
+	}
+	if err := config.WriteConfig2File(s.path); err != nil {
+		return err
+	}
+	s.config = &config
+	return nil
+}
+
+// 将接口中的配置写入 core.Config，接口地址和域名为空时使用飞书的默认值
+func (info ConfigInfo) applyTo(config *core.Config) {
+	config.Feishu.AppId = info.AppID
+	config.Feishu.AppSecret = info.AppSecret
+	config.Feishu.BaseURL = info.BaseURL
+	if config.Feishu.BaseURL == "" {
+		config.Feishu.BaseURL = core.DefaultBaseURL
+	}
+	config.Feishu.Domain = info.Domain
+	if config.Feishu.Domain == "" {
+		config.Feishu.Domain = core.DefaultDomain
+	}
+	if info.Output != nil {
+		config.Output = *info.Output
+		if info.OutputPath == "" {
+			return
+		}
+	}
+	config.Output.OutputPath = info.OutputPath
+}
+
+// 导出文件的保存目录，请求参数优先于配置
+func outputPathParam(c *gin.Context, config *core.Config) string {
+	if outputPath := c.Query("output_path"); outputPath != "" {
+		return outputPath
+	}
+	return configOutputPath(config)
+}
+
+func configOutputPath(config *core.Config) string {
+	if config.Output.OutputPath != "" {
+		return config.Output.OutputPath
+	}
+	return defaultOutputPath
+}
+
+// 保存配置处理函数
+func saveConfigHandler(c *gin.Context) {
+	var info ConfigInfo
+	if err := c.ShouldBindJSON(&info); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的配置数据"})
+		return
+	}
+	if info.Output != nil {
+		if err := info.Output.Image.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": fmt.Sprintf("图片处理参数无效: %s", err)})
+			return
+		}
+		if err := info.Output.Filename.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": fmt.Sprintf("文件名规则无效: %s", err)})
+			return
+		}
+	}
+	preview := loadConfig()
+	info.applyTo(preview)
+	if !checkConfigOutputPaths(c, &preview.Output) {
+		return
+	}
+
+	if err := serverConfig.update(info); err != nil {
+		log.Printf("保存配置失败: %s", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": fmt.Sprintf("保存配置失败: %s", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "配置已保存"})
+}
+
+// 获取配置处理函数，返回应用了环境变量和命令行参数后实际使用的配置
+func getConfigHandler(c *gin.Context) {
+	config := loadConfig()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"config": ConfigInfo{
+			AppID:      config.Feishu.AppId,
+			AppSecret:  config.Feishu.AppSecret,
+			OutputPath: config.Output.OutputPath,
+			BaseURL:    config.Feishu.BaseURL,
+			Domain:     config.Feishu.Domain,
+			Output:     &config.Output,
+		},
+		"profiles": config.ProfileNames(),
+	})
+}
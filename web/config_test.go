@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveConfigOutputPathSandbox(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output")
+	configPath := filepath.Join(dir, "config.json")
+	assert.NoError(t, initConfig(configPath, configOverrides{OutputPath: output}))
+	router := setupRouter("", "test-token", nil)
+
+	post := func(info ConfigInfo) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(info)
+		req := httptest.NewRequest(http.MethodPost, "/config", bytes.NewReader(body))
+		req.Header.Set("X-API-Key", "test-token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 没有设置 server.output_roots 时，保存的目录同样需要位于启动时的输出目录中
+	w := post(ConfigInfo{AppID: "id", OutputPath: "/"})
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.NoFileExists(t, configPath)
+
+	info := ConfigInfo{AppID: "id", OutputPath: output}
+	info.Output = &loadConfig().Output
+	info.Output.AssetStoreDir = filepath.Join(dir, "assets")
+	w = post(info)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+
+	// 保存后允许的目录不会改变
+	w = post(ConfigInfo{AppID: "id", OutputPath: filepath.Join(output, "docs")})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{output}, outputRoots())
+	assert.Equal(t, []string{output}, importRoots())
+}
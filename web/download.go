@@ -1,9 +1,8 @@
 package main
 
 import (
-	"archive/zip"
-	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,10 +10,10 @@ import (
 	"os"
 	"path/filepath"
 	"regexp" // 添加正则表达式包
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/88250/lute"
 	"github.com/Wsine/feishu2md/core"
 	"github.com/Wsine/feishu2md/utils"
 	"github.com/gin-gonic/gin"
@@ -28,81 +27,46 @@ func downloadHandler(c *gin.Context) {
 		return
 	}
 
-	// 获取输出路径参数
-	outputPath := c.Query("output_path")
-	if outputPath == "" {
-		outputPath = "output" // 默认输出路径
+	// 获取输出路径参数，未指定时使用配置中的输出目录
+	config, ok := requestConfig(c, feishu_docx_url)
+	if !ok {
+		return
+	}
+	outputPath, ok := sandboxPath(c, outputPathParam(c, config))
+	if !ok {
+		return
 	}
 
-	// 获取直接传递的token和type参数
-	directToken := c.Query("token")
-	directType := c.Query("type")
-
-	// 记录请求参数
-	log.Printf("下载请求参数: URL=%s, 输出路径=%s, 直接Token=%s, 直接类型=%s",
-		feishu_docx_url, outputPath, directToken, directType)
-
-	// 根据不同参数来源确定文档token和类型
-	var docType, docToken string
-
-	if directToken != "" {
-		// 优先使用直接传递的token和type
-		docToken = directToken
-		docType = directType
-		if docType == "" {
-			docType = "docx" // 默认类型
-		}
-		log.Printf("使用直接传递的参数: token=%s, type=%s", docToken, docType)
-	} else {
-		// 从URL解析token和type
-		var err error
-		docType, docToken, err = utils.ValidateDocumentURL(feishu_docx_url)
-		if err != nil {
-			log.Printf("URL验证失败: %s", err)
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"message": fmt.Sprintf("无效的文档URL: %s", err),
-			})
-			return
-		}
-		log.Printf("从URL解析的参数: token=%s, type=%s", docToken, docType)
+	log.Printf("下载请求参数: URL=%s, 输出路径=%s", feishu_docx_url, outputPath)
+	docType, docToken, err := documentParams(c, feishu_docx_url)
+	if err != nil {
+		log.Printf("URL验证失败: %s", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("无效的文档URL: %s", err),
+		})
+		return
 	}
 
 	fmt.Println("Captured document token:", docToken)
 
 	// Create client with context
 	ctx := context.Background()
-	config := core.NewConfig(
-		os.Getenv("FEISHU_APP_ID"),
-		os.Getenv("FEISHU_APP_SECRET"),
-	)
 
 	log.Printf("应用凭证: AppID=%s", config.Feishu.AppId)
 
-	client := core.NewClient(
-		config.Feishu.AppId, config.Feishu.AppSecret,
-	)
-
-	// Process the download
-	parser := core.NewParser(config.Output)
-	markdown := ""
+	client := newClient(config, feishu_docx_url)
 
 	// for a wiki page, we need to renew docType and docToken first
+	nodeToken := ""
 	if docType == "wiki" {
-		log.Printf("处理wiki类型文档，获取节点信息: token=%s", docToken)
-		node, err := client.GetWikiNodeInfo(ctx, docToken)
-		if err != nil {
-			log.Printf("获取知识库节点信息失败: %s", err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success": false,
-				"message": fmt.Sprintf("获取知识库节点信息失败: %s", err),
-			})
-			return
-		}
-		log.Printf("获取到节点信息: 标题=%s, 对象类型=%s, 对象Token=%s",
-			node.Title, node.ObjType, node.ObjToken)
-		docType = node.ObjType
-		docToken = node.ObjToken
+		nodeToken = docToken
+	}
+	docType, docToken, spaceID, err := resolveWikiDocument(ctx, client, docType, docToken)
+	if err != nil {
+		log.Printf("获取知识库节点信息失败: %s", err)
+		respondAPIError(c, "获取知识库节点信息失败", err)
+		return
 	}
 
 	if docType == "docs" {
@@ -114,182 +78,180 @@ func downloadHandler(c *gin.Context) {
 		return
 	}
 
-	// 获取文档内容
-	log.Printf("开始获取文档内容: token=%s, type=%s", docToken, docType)
-	docx, blocks, err := client.GetDocxContent(ctx, docToken)
-
-	// 这里是原来出错的地方，改进错误处理
-	if err != nil {
-		// 记录详细错误信息
-		log.Printf("获取文档内容失败: %s", err)
-
-		// 检查是否是404错误
-		if strings.Contains(err.Error(), "404") {
-			c.JSON(http.StatusNotFound, gin.H{
-				"success": false,
-				"message": "文档不存在或无权访问",
-				"error":   err.Error(),
-			})
+	if docType == "sheet" || docType == "sheets" || docType == "bitable" || docType == "base" {
+		dir, ok := outputDir(c, outputPath)
+		if !ok {
+			return
+		}
+		// 与新版文档共用输出目录的同步状态，不会覆盖其他文档导出的同名文件
+		state, err := core.OpenSyncState(outputPath)
+		if err != nil {
+			log.Printf("读取同步状态失败: %s", err)
+			state = nil
+		}
+		target := exportTarget{Dir: dir, NodeToken: nodeToken, Namer: newFileNamer(config.Output.Filename, state)}
+		if docType == "sheet" || docType == "sheets" {
+			downloadSheet(c, ctx, client, docToken, target, c.DefaultQuery("sheet_format", config.Output.SheetFormat))
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success": false,
-				"message": "获取文档内容失败",
-				"error":   err.Error(),
-			})
+			downloadBitable(c, ctx, client, docToken, target, assetConcurrency(c, config))
 		}
 		return
 	}
 
-	// 记录文档基本信息
-	log.Printf("成功获取文档内容: 标题=%s, 块数量=%d", docx.Title, len(blocks))
-
-	markdown = parser.ParseDocxContent(docx, blocks)
-
-	// 获取文档标题并处理为合法文件名
-	docTitle := docx.Title
-	if docTitle == "" {
-		docTitle = docToken // 如果标题为空，仍使用token作为备选
-	}
-	// 替换文件名中的非法字符
-	docTitle = sanitizeFilename(docTitle)
-
 	// 获取自定义路径参数
-	customPath := c.Query("path")
-	log.Printf("自定义路径参数: %s", customPath)
-
-	// 如果提供了自定义路径，创建对应的目录结构
-	if customPath != "" {
-		// 解码路径
-		decodedPath, err := url.QueryUnescape(customPath)
-		if err != nil {
-			log.Printf("路径解码失败: %s", err)
-			decodedPath = customPath
-		}
-
-		// 构建完整输出路径
-		fullOutputPath := filepath.Join(outputPath, decodedPath)
-		log.Printf("使用自定义路径: %s", fullOutputPath)
-
-		// 创建目录
-		if err := os.MkdirAll(fullOutputPath, 0755); err != nil {
-			log.Printf("创建自定义路径目录失败: %s", err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success": false,
-				"message": fmt.Sprintf("创建自定义路径目录失败: %s", err),
-			})
-			return
-		}
-
-		// 更新文件保存路径
-		outputPath = fullOutputPath
+	docDir, ok := outputDir(c, outputPath)
+	if !ok {
+		return
 	}
-
-	// 为当前文档创建专属的图片目录
-	docImagesDir := filepath.Join(outputPath, docTitle+"_images")
-	log.Printf("为文档创建专属图片目录: %s", docImagesDir)
-	if err := os.MkdirAll(docImagesDir, 0755); err != nil {
-		log.Printf("创建文档图片目录失败: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
+	imageOpts, err := imageOptions(c, config)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": fmt.Sprintf("创建文档图片目录失败: %s", err),
+			"message": fmt.Sprintf("图片处理参数无效: %s", err),
 		})
 		return
 	}
 
-	// 更新配置中的图片输出路径为文档专属目录
-	config.Output.ImageDir = docImagesDir
-
-	// 处理图片
-	log.Printf("开始处理文档中的图片，共 %d 个", len(parser.ImgTokens))
-	zipBuffer := new(bytes.Buffer)
-	writer := zip.NewWriter(zipBuffer)
-	for i, imgToken := range parser.ImgTokens {
-		log.Printf("处理图片 %d/%d: token=%s", i+1, len(parser.ImgTokens), imgToken)
-
-		// 使用文档专属的图片目录
-		localLink, rawImage, err := client.DownloadImageRaw(ctx, imgToken, config.Output.ImageDir)
-		if err != nil {
-			log.Printf("下载图片失败: %s", err)
-			// 继续处理其他图片，而不是中断整个过程
-			continue
-		}
+	storeDir, ok := assetStoreParam(c, config)
+	if !ok {
+		return
+	}
 
-		// 确保图片文件被写入磁盘
-		imgFilePath := filepath.Join(config.Output.ImageDir, filepath.Base(localLink))
-		err = os.WriteFile(imgFilePath, rawImage, 0644)
-		if err != nil {
-			log.Printf("保存图片文件失败: %s", err)
-			continue
-		}
+	// 输出目录中记录了每个文档导出时的版本，版本没有变化的文档不再重新下载
+	state, err := core.OpenSyncState(outputPath)
+	if err != nil {
+		log.Printf("读取同步状态失败，重新导出文档: %s", err)
+		state = nil
+	}
+	result, err := exportDocx(ctx, client, config, state, docToken, docxExportOptions{
+		Dir:          docDir,
+		NodeToken:    nodeToken,
+		Space:        spaceID,
+		Force:        c.Query("force") == "true",
+		Concurrency:  assetConcurrency(c, config),
+		Image:        imageOpts,
+		SinkType:     c.DefaultQuery("asset_sink", config.Output.AssetSink.Type),
+		StoreDir:     storeDir,
+		DocumentJSON: c.DefaultQuery("document_json", strconv.FormatBool(config.Output.DocumentJSON)) == "true",
+	})
+	if err != nil {
+		log.Printf("导出文档失败: %s", err)
+		respondAPIError(c, "导出文档失败", err)
+		return
+	}
 
-		// 验证文件是否成功写入
-		if _, err := os.Stat(imgFilePath); os.IsNotExist(err) {
-			log.Printf("图片文件写入验证失败，文件不存在: %s", imgFilePath)
-			continue
-		}
+	log.Printf("文档下载和保存成功: %s", result.Path)
+	response := gin.H{
+		"success":   true,
+		"message":   "文档下载成功",
+		"file_path": result.Path,
+		"status":    result.Status,
+	}
+	if result.Status != exportStatusExported {
+		response["message"] = "文档没有更新"
+	}
+	if len(result.AssetErrors) > 0 {
+		response["asset_errors"] = result.AssetErrors
+	}
+	if result.JSONPath != "" {
+		response["json_path"] = result.JSONPath
+	}
 
-		// 修改Markdown中的图片引用路径为相对路径
-		relativeImgPath := filepath.Base(docImagesDir) + "/" + filepath.Base(localLink)
-		log.Printf("图片下载成功: %s，在Markdown中使用相对路径: %s", imgFilePath, relativeImgPath)
-		markdown = strings.Replace(markdown, imgToken, relativeImgPath, 1)
+	// 返回成功响应
+	c.JSON(http.StatusOK, response)
+}
 
-		// 添加到ZIP文件
-		f, err := writer.Create(localLink)
-		if err != nil {
-			log.Printf("创建ZIP文件条目失败: %s", err)
-			continue
-		}
-		_, err = f.Write(rawImage)
-		if err != nil {
-			log.Printf("写入图片数据失败: %s", err)
-			continue
+// 根据请求参数确定文档类型和 token，直接传递的 token 和 type 参数优先于文档链接
+func documentParams(c *gin.Context, docURL string) (string, string, error) {
+	if directToken := c.Query("token"); directToken != "" {
+		docType := c.Query("type")
+		if docType == "" {
+			docType = "docx" // 默认类型
 		}
+		log.Printf("使用直接传递的参数: token=%s, type=%s", directToken, docType)
+		return docType, directToken, nil
 	}
+	docType, docToken, err := utils.ValidateDocumentURL(docURL)
+	if err != nil {
+		return "", "", err
+	}
+	log.Printf("从URL解析的参数: token=%s, type=%s", docToken, docType)
+	return docType, docToken, nil
+}
 
-	engine := lute.New(func(l *lute.Lute) {
-		l.RenderOptions.AutoSpace = true
-	})
-	result := engine.FormatStr("md", markdown)
-
-	// 保存Markdown文件到本地
-	mdFilePath := filepath.Join(outputPath, docTitle+".md")
-	log.Printf("保存Markdown文件到: %s", mdFilePath)
-	err = os.WriteFile(mdFilePath, []byte(result), 0644)
+// 知识库节点解析为节点对应的文档，同时返回所在的知识空间，其他类型原样返回
+func resolveWikiDocument(ctx context.Context, client *core.Client, docType, docToken string) (string, string, string, error) {
+	if docType != "wiki" {
+		return docType, docToken, "", nil
+	}
+	log.Printf("处理wiki类型文档，获取节点信息: token=%s", docToken)
+	node, err := client.GetWikiNodeInfo(ctx, docToken)
 	if err != nil {
-		log.Printf("保存Markdown文件失败: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": fmt.Sprintf("保存Markdown文件失败: %s", err),
-		})
-		return
+		return "", "", "", err
 	}
+	log.Printf("获取到节点信息: 标题=%s, 对象类型=%s, 对象Token=%s",
+		node.Title, node.ObjType, node.ObjToken)
+	return node.ObjType, node.ObjToken, node.SpaceID, nil
+}
 
-	log.Printf("文档下载和保存成功: %s", mdFilePath)
+// 同时下载的图片和附件数量，请求参数 asset_concurrency 优先于配置
+func assetConcurrency(c *gin.Context, config *core.Config) int {
+	concurrency, err := strconv.Atoi(c.Query("asset_concurrency"))
+	if err != nil || concurrency <= 0 {
+		return config.Output.AssetConcurrency
+	}
+	return concurrency
+}
 
-	// 返回成功响应
-	c.JSON(http.StatusOK, gin.H{
-		"success":   true,
-		"message":   "文档下载成功",
-		"file_path": mdFilePath,
-	})
+// 读取本次导出的图片处理选项，查询参数 image_format、image_max_width 和 image_quality 覆盖配置文件中的设置
+func imageOptions(c *gin.Context, config *core.Config) (core.ImageOptions, error) {
+	options := config.Output.Image
+	options.Format = c.DefaultQuery("image_format", options.Format)
+	for name, value := range map[string]*int{"image_max_width": &options.MaxWidth, "image_quality": &options.Quality} {
+		if param := c.Query(name); param != "" {
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				return options, fmt.Errorf("%s: %w", name, err)
+			}
+			*value = n
+		}
+	}
+	return options, options.Validate()
 }
 
-// 处理文件名中的非法字符
-func sanitizeFilename(filename string) string {
-	// 替换Windows文件名中不允许的字符: \ / : * ? " < > |
-	invalidChars := []string{"\\", "/", ":", "*", "?", "\"", "<", ">", "|"}
-	result := filename
+// 根据自定义路径参数构建输出目录，检查目录位于允许写入的目录中，并确保目录存在
+func resolveOutputPath(outputPath, customPath string) (string, error) {
+	log.Printf("自定义路径参数: %s", customPath)
+	fullOutputPath := outputPath
+	if customPath != "" {
+		// 解码路径
+		decodedPath, err := url.QueryUnescape(customPath)
+		if err != nil {
+			log.Printf("路径解码失败: %s", err)
+			decodedPath = customPath
+		}
 
-	for _, char := range invalidChars {
-		result = strings.ReplaceAll(result, char, "_")
+		// 构建完整输出路径
+		fullOutputPath = filepath.Join(outputPath, decodedPath)
+		log.Printf("使用自定义路径: %s", fullOutputPath)
 	}
 
-	// 限制文件名长度，避免过长
-	if len(result) > 100 {
-		result = result[:100]
+	fullOutputPath, err := checkOutputPath(fullOutputPath, outputRoots())
+	if err != nil {
+		return "", err
 	}
 
-	return result
+	// 创建目录
+	if err := os.MkdirAll(fullOutputPath, 0755); err != nil {
+		log.Printf("创建自定义路径目录失败: %s", err)
+		return "", err
+	}
+	return fullOutputPath, nil
+}
+
+// 处理文件名中的非法字符，用于知识空间名称等不使用文件名模板的目录
+func sanitizeFilename(filename string) string {
+	return core.SanitizeFilename(filename, core.DefaultFilenameMaxBytes)
 }
 
 // 文档节点结构，用于构建树状结构
@@ -319,10 +281,14 @@ func getWikiDocsHandler(c *gin.Context) {
 		return
 	}
 
-	// 获取输出路径参数
-	outputPath := c.Query("output_path")
-	if outputPath == "" {
-		outputPath = "output" // 默认输出路径
+	// 获取输出路径参数，未指定时使用配置中的输出目录
+	config, ok := requestConfig(c, wikiURL)
+	if !ok {
+		return
+	}
+	outputPath, ok := sandboxPath(c, outputPathParam(c, config))
+	if !ok {
+		return
 	}
 
 	// 验证知识库URL
@@ -337,18 +303,13 @@ func getWikiDocsHandler(c *gin.Context) {
 
 	// 创建客户端
 	ctx := context.Background()
-	config := core.NewConfig(
-		os.Getenv("FEISHU_APP_ID"),
-		os.Getenv("FEISHU_APP_SECRET"),
-	)
-	client := core.NewClient(
-		config.Feishu.AppId, config.Feishu.AppSecret,
-	)
+	client := newClient(config, wikiURL)
 
 	// 获取知识库根节点
 	rootNode, err := client.GetWikiNodeInfo(ctx, spaceToken)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, WikiDocsTreeResponse{
+		status, _ := apiErrorStatus(err)
+		c.JSON(status, WikiDocsTreeResponse{
 			Success: false,
 			Message: fmt.Sprintf("获取知识库信息失败: %s", err),
 		})
@@ -371,15 +332,16 @@ func getWikiDocsHandler(c *gin.Context) {
 	// 创建根文档节点
 	docTree := &DocNode{
 		Title:    spaceName,
-		URL:      fmt.Sprintf("https://feishu.cn/wiki/%s", rootNode.NodeToken),
+		URL:      client.DocumentURL("wiki", rootNode.NodeToken),
 		Type:     "space",
 		Children: []*DocNode{},
 	}
 
 	// 获取知识库中的顶级节点列表
-	topNodes, err := client.GetWikiNodeList(ctx, rootNode.SpaceID, nil)
+	topNodes, err := client.ListWikiNodes(ctx, rootNode.SpaceID, "")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, WikiDocsTreeResponse{
+		status, _ := apiErrorStatus(err)
+		c.JSON(status, WikiDocsTreeResponse{
 			Success: false,
 			Message: fmt.Sprintf("获取知识库顶级节点失败: %s", err),
 		})
@@ -388,48 +350,51 @@ func getWikiDocsHandler(c *gin.Context) {
 
 	fmt.Printf("知识库有 %d 个顶级节点\n", len(topNodes))
 
-	// 处理每个顶级节点
-	for _, item := range topNodes {
-		topNode := &core.WikiNode{
-			NodeToken: item.NodeToken,
-			ObjToken:  item.ObjToken,
-			ObjType:   item.ObjType,
-			Title:     item.Title,
-			SpaceID:   rootNode.SpaceID,
-		}
-
-		// 创建顶级文档节点
-		docNode := &DocNode{
-			Title:    topNode.Title,
-			Children: []*DocNode{},
-		}
-
-		// 设置URL和类型
-		if topNode.ObjType == "docx" || topNode.ObjType == "doc" {
-			docNode.URL = fmt.Sprintf("https://feishu.cn/docx/%s", topNode.ObjToken)
-			docNode.Type = topNode.ObjType
-		} else {
-			docNode.URL = fmt.Sprintf("https://feishu.cn/wiki/%s", topNode.NodeToken)
-			docNode.Type = "folder"
+	// 并发遍历节点树，stream=true 时每发现一个节点就输出一行 JSON，最后一行为完整的文档树
+	options := core.WikiCrawlOptions{Workers: config.Output.CrawlWorkers}
+	if workers, err := strconv.Atoi(c.Query("workers")); err == nil && workers > 0 {
+		options.Workers = workers
+	}
+	if maxDepth, err := strconv.Atoi(c.Query("max_depth")); err == nil && maxDepth > 0 {
+		options.MaxDepth = maxDepth
+	}
+	stream := c.Query("stream") == "true"
+	respond := c.JSON
+	if stream {
+		c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+		c.Status(http.StatusOK)
+		encoder := json.NewEncoder(c.Writer)
+		options.OnNode = func(node *core.WikiTreeNode) {
+			nodeType, nodeURL := wikiNodeTypeAndURL(client, node.WikiNode)
+			encoder.Encode(gin.H{
+				"event":             "node",
+				"title":             node.Title,
+				"node_token":        node.NodeToken,
+				"parent_node_token": node.ParentNodeToken,
+				"depth":             node.Depth,
+				"type":              nodeType,
+				"url":               nodeURL,
+			})
+			c.Writer.Flush()
 		}
-
-		// 递归构建文档树
-		err = buildDocTree(ctx, client, topNode, docNode)
-		if err != nil {
-			fmt.Printf("处理顶级节点 %s 失败: %s\n", topNode.Title, err)
-			// 继续处理其他节点
+		// 响应头已经发送，结果作为最后一行输出
+		respond = func(_ int, result interface{}) {
+			encoder.Encode(gin.H{"event": "done", "result": result})
+			c.Writer.Flush()
 		}
-
-		// 添加到根节点
-		docTree.Children = append(docTree.Children, docNode)
 	}
 
+	start := time.Now()
+	tree := client.CrawlWikiTree(ctx, topNodes, options)
+	docTree.Children = docNodesFromTree(client, tree)
+	log.Printf("文档树遍历完成，耗时 %v", time.Since(start))
+
 	// 生成树状结构的文本文件
 	treeText := generateTreeText(docTree, 0)
 
 	// 确保输出目录存在
 	if err := os.MkdirAll(outputPath, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, WikiDocsTreeResponse{
+		respond(http.StatusInternalServerError, WikiDocsTreeResponse{
 			Success: false,
 			Message: fmt.Sprintf("创建输出目录失败: %s", err),
 		})
@@ -444,7 +409,7 @@ func getWikiDocsHandler(c *gin.Context) {
 	fmt.Printf("完整文件路径: %s\n", treeFilePath)
 	err = os.WriteFile(treeFilePath, []byte(treeText), 0644)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, WikiDocsTreeResponse{
+		respond(http.StatusInternalServerError, WikiDocsTreeResponse{
 			Success: false,
 			Message: fmt.Sprintf("保存文档树文件失败: %s", err),
 		})
@@ -452,77 +417,54 @@ func getWikiDocsHandler(c *gin.Context) {
 	}
 
 	// 返回文档树
-	c.JSON(http.StatusOK, WikiDocsTreeResponse{
+	respond(http.StatusOK, WikiDocsTreeResponse{
 		Success: true,
 		Message: fmt.Sprintf("成功生成文档树，已保存到 %s", treeFilePath),
 		Tree:    docTree,
 	})
 }
 
-// 递归构建文档树
-func buildDocTree(ctx context.Context, client *core.Client, node *core.WikiNode, docNode *DocNode) error {
-	// 打印当前处理的节点信息，帮助调试
-	fmt.Printf("处理节点: %s, 类型: %s\n", node.Title, node.ObjType)
-
-	// 添加延迟，避免触发限速
-	time.Sleep(100 * time.Millisecond)
-
-	// 获取子节点，添加重试机制
-	var children []*core.WikiNode
-	var err error
-	maxRetries := 3
-
-	for i := 0; i < maxRetries; i++ {
-		children, err = client.GetWikiNodeChildren(ctx, node.NodeToken)
-		if err == nil {
-			break
+// 将遍历得到的节点树转换为文档树，获取子节点失败的节点保留在树中
+func docNodesFromTree(client *core.Client, nodes []*core.WikiTreeNode) []*DocNode {
+	docNodes := make([]*DocNode, 0, len(nodes))
+	for _, node := range nodes {
+		if node.Err != nil {
+			log.Printf("获取节点 %s 的子节点失败: %s", node.Title, node.Err)
 		}
-
-		if strings.Contains(err.Error(), "frequency limit") {
-			retryDelay := time.Duration(1<<uint(i)) * time.Second // 指数退避: 1s, 2s, 4s
-			log.Printf("触发限速，等待 %v 后重试 (%d/%d)...", retryDelay, i+1, maxRetries)
-			time.Sleep(retryDelay)
-		} else {
-			log.Printf("获取节点 %s 的子节点失败: %s", node.Title, err)
-			return nil // 非限速错误，继续处理其他节点
+		docNode := &DocNode{
+			Title:    node.Title,
+			Children: docNodesFromTree(client, node.Children),
 		}
+		docNode.Type, docNode.URL = wikiNodeTypeAndURL(client, node.WikiNode)
+		docNodes = append(docNodes, docNode)
 	}
+	return docNodes
+}
 
-	if err != nil {
-		log.Printf("获取节点 %s 的子节点失败，已重试 %d 次: %s", node.Title, maxRetries, err)
-		return nil // 继续处理其他节点
+// 根据知识库节点的对象类型，返回前端展示的节点类型和链接
+func wikiNodeTypeAndURL(client *core.Client, node *core.WikiNode) (string, string) {
+	switch node.ObjType {
+	case "docx", "doc":
+		return node.ObjType, client.DocumentURL("docx", node.ObjToken)
+	case "sheet":
+		return node.ObjType, client.DocumentURL("sheets", node.ObjToken)
+	case "bitable":
+		return node.ObjType, client.DocumentURL("base", node.ObjToken)
+	case "mindnote":
+		return node.ObjType, client.DocumentURL("mindnotes", node.ObjToken)
+	default:
+		return "folder", client.DocumentURL("wiki", node.NodeToken)
 	}
+}
 
-	fmt.Printf("节点 %s 有 %d 个子节点\n", node.Title, len(children))
-
-	// 递归处理子节点
-	for _, child := range children {
-		childNode := &DocNode{
-			Title:    child.Title,
-			Children: []*DocNode{},
-		}
-
-		// 设置URL和类型
-		if child.ObjType == "docx" || child.ObjType == "doc" {
-			childNode.URL = fmt.Sprintf("https://feishu.cn/docx/%s", child.ObjToken)
-			childNode.Type = child.ObjType
-		} else {
-			childNode.URL = fmt.Sprintf("https://feishu.cn/wiki/%s", child.NodeToken)
-			childNode.Type = "folder"
-		}
-
-		// 递归处理子节点
-		err := buildDocTree(ctx, client, child, childNode)
-		if err != nil {
-			log.Printf("处理子节点 %s 失败: %s", child.Title, err)
-			// 继续处理其他子节点，不中断
-		}
-
-		// 添加到父节点
-		docNode.Children = append(docNode.Children, childNode)
+// 是否为可以导出的文档类型节点
+func isDocumentType(nodeType string) bool {
+	switch nodeType {
+	case "docx", "doc", "sheet", "bitable":
+		return true
+	default:
+		return false
 	}
-
-	return nil
 }
 
 // 生成树状结构的文本
@@ -533,7 +475,7 @@ func generateTreeText(node *DocNode, level int) string {
 	indent := strings.Repeat("  ", level)
 
 	// 添加当前节点
-	if node.Type == "docx" || node.Type == "doc" {
+	if isDocumentType(node.Type) {
 		result.WriteString(fmt.Sprintf("%s- [%s](%s)\n", indent, node.Title, node.URL))
 	} else {
 		result.WriteString(fmt.Sprintf("%s- **%s**\n", indent, node.Title))
@@ -557,15 +499,13 @@ func getWikiSpaceInfoHandler(c *gin.Context) {
 
 	// 创建客户端
 	ctx := context.Background()
-	config := core.NewConfig(
-		os.Getenv("FEISHU_APP_ID"),
-		os.Getenv("FEISHU_APP_SECRET"),
-	)
+	config, ok := requestConfig(c, utils.UnescapeURL(wikiURL))
+	if !ok {
+		return
+	}
 	log.Printf("应用凭证: AppID=%s, AppSecret=%s", config.Feishu.AppId, "***")
 
-	client := core.NewClient(
-		config.Feishu.AppId, config.Feishu.AppSecret,
-	)
+	client := newClient(config, utils.UnescapeURL(wikiURL))
 
 	// 根据不同参数获取空间信息
 	var spaceName string
@@ -590,7 +530,7 @@ func getWikiSpaceInfoHandler(c *gin.Context) {
 		}
 
 		// 检查是否是空间URL (形如 https://feishu.cn/wiki/space/7398737263215149060)
-		spaceURLPattern := regexp.MustCompile(`https://feishu\.cn/wiki/space/(\d+)`)
+		spaceURLPattern := regexp.MustCompile(`https://[\w-.]+/wiki/space/(\d+)`)
 		matches := spaceURLPattern.FindStringSubmatch(wikiURL)
 
 		if len(matches) > 1 {
@@ -616,10 +556,7 @@ func getWikiSpaceInfoHandler(c *gin.Context) {
 			rootNode, err := client.GetWikiNodeInfo(ctx, spaceToken)
 			if err != nil {
 				log.Printf("获取知识库根节点失败: %s", err)
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"success": false,
-					"message": fmt.Sprintf("获取知识库信息失败: %s", err),
-				})
+				respondAPIError(c, "获取知识库信息失败", err)
 				return
 			}
 
@@ -647,10 +584,7 @@ func getWikiSpaceInfoHandler(c *gin.Context) {
 		spaceName, err = client.GetWikiName(ctx, spaceIDToUse)
 		if err != nil {
 			log.Printf("通过 space_id 获取空间名称失败: %s", err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success": false,
-				"message": fmt.Sprintf("获取知识库空间名称失败: %s", err),
-			})
+			respondAPIError(c, "获取知识库空间名称失败", err)
 			return
 		}
 		log.Printf("通过 space_id 获取空间名称成功: %s", spaceName)
@@ -704,124 +638,42 @@ func getWikiTopNodesHandler(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	config := core.NewConfig(
-		os.Getenv("FEISHU_APP_ID"),
-		os.Getenv("FEISHU_APP_SECRET"),
-	)
+	config, ok := requestConfig(c, "")
+	if !ok {
+		return
+	}
 	log.Printf("应用凭证: AppID=%s", config.Feishu.AppId)
 
-	client := core.NewClient(
-		config.Feishu.AppId, config.Feishu.AppSecret,
-	)
+	client := newClient(config, "")
 
-	// 获取知识库中的顶级节点列表，添加重试机制
+	// 分页获取知识库中的顶级节点列表，限流和临时错误由客户端重试
 	var topNodes []*core.WikiNode
-	var err error
-	maxRetries := 5           // 增加重试次数
-	var pageToken string = "" // 添加分页支持
-
-	// 记录所有分页的节点
-	allNodes := []*core.WikiNode{}
-
-	// 分页获取所有节点
+	var pageToken *string
 	for {
-		// 尝试获取当前页的节点
-		pageSuccess := false
-		for i := 0; i < maxRetries; i++ {
-			log.Printf("尝试获取顶级节点 (页码标记: %s) (%d/%d)...", pageToken, i+1, maxRetries)
-
-			// 为每次请求创建新的上下文，避免使用已超时的上下文
-			reqCtx, reqCancel := context.WithTimeout(context.Background(), 60*time.Second)
-
-			// 记录开始时间，用于计算耗时
-			startTime := time.Now()
-
-			// 获取节点列表并转换类型
-			var rawNodes []*core.WikiNode
-			var nextPageToken string
-
-			// 使用分页参数
-			if pageToken == "" {
-				rawNodes, nextPageToken, err = client.GetWikiNodeListWithPagination(reqCtx, spaceID, nil)
-			} else {
-				rawNodes, nextPageToken, err = client.GetWikiNodeListWithPagination(reqCtx, spaceID, &pageToken)
-			}
-
-			reqCancel() // 请求完成后取消上下文
-
-			// 计算耗时
-			elapsed := time.Since(startTime)
-			log.Printf("获取顶级节点请求耗时: %v", elapsed)
-
-			if err == nil {
-				// 将当前页节点添加到总列表
-				allNodes = append(allNodes, rawNodes...)
-				log.Printf("成功获取顶级节点页，本页 %d 个节点，当前总计 %d 个节点", len(rawNodes), len(allNodes))
-
-				// 更新分页标记
-				pageToken = nextPageToken
-				pageSuccess = true
-				break
-			}
-
-			// 详细记录错误信息
-			log.Printf("获取顶级节点页失败 (%d/%d): %s", i+1, maxRetries, err)
-
-			if strings.Contains(err.Error(), "frequency limit") {
-				retryDelay := time.Duration(2<<uint(i)) * time.Second // 指数退避: 2s, 4s, 8s, 16s, 32s
-				log.Printf("触发限速，等待 %v 后重试...", retryDelay)
-				time.Sleep(retryDelay)
-			} else if strings.Contains(err.Error(), "context deadline exceeded") ||
-				strings.Contains(err.Error(), "timeout") {
-				// 超时错误增加等待时间再重试
-				retryDelay := time.Duration(5*(i+1)) * time.Second // 5s, 10s, 15s, 20s, 25s
-				log.Printf("请求超时，等待 %v 后重试...", retryDelay)
-				time.Sleep(retryDelay)
-			} else {
-				// 其他错误也尝试重试
-				retryDelay := time.Duration(3<<uint(i)) * time.Second
-				log.Printf("遇到错误，等待 %v 后重试...", retryDelay)
-				time.Sleep(retryDelay)
-			}
+		// 为每页请求单独设置超时
+		reqCtx, reqCancel := context.WithTimeout(context.Background(), 60*time.Second)
+		pageNodes, nextPageToken, err := client.GetWikiNodeListWithPagination(reqCtx, spaceID, pageToken)
+		reqCancel()
+		if err != nil {
+			log.Printf("获取顶级节点页失败，已获取 %d 个节点: %s", len(topNodes), err)
+			respondAPIError(c, "获取知识库顶级节点失败", err)
+			return
 		}
 
-		// 如果当前页获取失败，返回错误
-		if !pageSuccess {
-			log.Printf("获取顶级节点页最终失败，已获取 %d 个节点", len(allNodes))
-			if len(allNodes) > 0 {
-				// 如果已经获取了一些节点，继续处理
-				log.Printf("尽管有错误，但已获取部分节点，将继续处理")
-				topNodes = allNodes
-				break
-			} else {
-				// 如果一个节点都没获取到，返回错误
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"success":       false,
-					"message":       fmt.Sprintf("获取知识库顶级节点失败: %s", err),
-					"error_details": err.Error(),
-				})
-				return
-			}
-		}
+		topNodes = append(topNodes, pageNodes...)
+		log.Printf("成功获取顶级节点页，本页 %d 个节点，当前总计 %d 个节点", len(pageNodes), len(topNodes))
 
 		// 如果没有下一页，结束循环
-		if pageToken == "" {
-			log.Printf("所有页面获取完成，共 %d 个节点", len(allNodes))
-			topNodes = allNodes
+		if nextPageToken == "" {
 			break
 		}
+		pageToken = &nextPageToken
 	}
 
 	// 构建返回数据
 	var nodes []gin.H
 	for i, item := range topNodes {
-		nodeType := "folder"
-		nodeURL := fmt.Sprintf("https://feishu.cn/wiki/%s", item.NodeToken)
-
-		if item.ObjType == "docx" || item.ObjType == "doc" {
-			nodeType = item.ObjType
-			nodeURL = fmt.Sprintf("https://feishu.cn/docx/%s", item.ObjToken)
-		}
+		nodeType, nodeURL := wikiNodeTypeAndURL(client, item)
 
 		log.Printf("顶级节点 %d: 标题=%s, 类型=%s", i+1, item.Title, nodeType)
 
@@ -856,56 +708,23 @@ func getWikiNodeChildrenHandler(c *gin.Context) {
 
 	// 创建客户端
 	ctx := context.Background()
-	config := core.NewConfig(
-		os.Getenv("FEISHU_APP_ID"),
-		os.Getenv("FEISHU_APP_SECRET"),
-	)
-	client := core.NewClient(
-		config.Feishu.AppId, config.Feishu.AppSecret,
-	)
-
-	// 获取子节点，添加重试机制
-	var children []*core.WikiNode
-	var err error
-	maxRetries := 3
-
-	for i := 0; i < maxRetries; i++ {
-		children, err = client.GetWikiNodeChildren(ctx, nodeToken)
-		if err == nil {
-			break
-		}
-
-		if strings.Contains(err.Error(), "frequency limit") {
-			retryDelay := time.Duration(1<<uint(i)) * time.Second // 指数退避: 1s, 2s, 4s
-			log.Printf("触发限速，等待 %v 后重试 (%d/%d)...", retryDelay, i+1, maxRetries)
-			time.Sleep(retryDelay)
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success": false,
-				"message": fmt.Sprintf("获取节点子节点失败: %s", err),
-			})
-			return
-		}
+	config, ok := requestConfig(c, "")
+	if !ok {
+		return
 	}
+	client := newClient(config, "")
 
+	// 获取子节点，限流和临时错误由客户端重试
+	children, err := client.GetWikiNodeChildren(ctx, nodeToken)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": fmt.Sprintf("获取节点子节点失败，已重试 %d 次: %s", maxRetries, err),
-		})
+		respondAPIError(c, "获取节点子节点失败", err)
 		return
 	}
 
 	// 构建返回数据
 	var nodes []gin.H
 	for _, child := range children {
-		nodeType := "folder"
-		nodeURL := fmt.Sprintf("https://feishu.cn/wiki/%s", child.NodeToken)
-
-		if child.ObjType == "docx" || child.ObjType == "doc" {
-			nodeType = child.ObjType
-			nodeURL = fmt.Sprintf("https://feishu.cn/docx/%s", child.ObjToken)
-		}
+		nodeType, nodeURL := wikiNodeTypeAndURL(client, child)
 
 		nodes = append(nodes, gin.H{
 			"title":      child.Title,
@@ -942,7 +761,15 @@ func saveWikiTreeHandler(c *gin.Context) {
 
 	outputPath := request.OutputPath
 	if outputPath == "" {
-		outputPath = "output" // 默认输出路径
+		config, ok := requestConfig(c, "")
+		if !ok {
+			return
+		}
+		outputPath = configOutputPath(config)
+	}
+	outputPath, ok := sandboxPath(c, outputPath)
+	if !ok {
+		return
 	}
 
 	// 生成树状结构的文本文件
@@ -983,25 +810,20 @@ func getAllWikiSpacesHandler(c *gin.Context) {
 
 	// 创建客户端
 	ctx := context.Background()
-	config := core.NewConfig(
-		os.Getenv("FEISHU_APP_ID"),
-		os.Getenv("FEISHU_APP_SECRET"),
-	)
+	config, ok := requestConfig(c, "")
+	if !ok {
+		return
+	}
 	log.Printf("应用凭证: AppID=%s, AppSecret=%s", config.Feishu.AppId, "***")
 
-	client := core.NewClient(
-		config.Feishu.AppId, config.Feishu.AppSecret,
-	)
+	client := newClient(config, "")
 
 	// 获取所有知识库空间列表
 	log.Printf("开始获取所有知识库空间列表...")
 	spaces, err := client.GetAllWikiSpaces(ctx)
 	if err != nil {
 		log.Printf("获取知识库空间列表失败: %s", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": fmt.Sprintf("获取知识库空间列表失败: %s", err),
-		})
+		respondAPIError(c, "获取知识库空间列表失败", err)
 		return
 	}
 
@@ -1014,7 +836,7 @@ func getAllWikiSpacesHandler(c *gin.Context) {
 		spacesList = append(spacesList, gin.H{
 			"space_id":   space.SpaceID,
 			"space_name": space.Name,
-			"url":        fmt.Sprintf("https://feishu.cn/wiki/space/%s", space.SpaceID),
+			"url":        client.DocumentURL("wiki/space", space.SpaceID),
 		})
 	}
 
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// 模拟知识库接口：space 下有文档 A（子节点 A1，A1 下还有 A2）和指向 A 的快捷方式
+func newWikiDocsServer(t *testing.T) (*httptest.Server, *[]string) {
+	children := map[string][]map[string]any{
+		"":   {{"node_token": "A", "title": "A", "obj_type": "docx", "obj_token": "docA", "node_type": "origin", "has_child": true}, {"node_token": "S", "title": "Link", "obj_type": "docx", "obj_token": "docA", "node_type": "shortcut", "origin_node_token": "A", "has_child": true}},
+		"A":  {{"node_token": "A1", "title": "A1", "obj_type": "docx", "obj_token": "docA1", "node_type": "origin", "has_child": true}},
+		"A1": {{"node_token": "A2", "title": "A2", "obj_type": "docx", "obj_token": "docA2", "node_type": "origin"}},
+	}
+	var mu sync.Mutex
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/tenant_access_token/internal"):
+			fmt.Fprint(w, `{"code":0,"msg":"ok","tenant_access_token":"t-g1044abcdefghijk","expire":7200}`)
+		case strings.HasSuffix(r.URL.Path, "/spaces/get_node"):
+			fmt.Fprint(w, `{"code":0,"msg":"ok","data":{"node":{"space_id":"space","node_token":"root","title":"Root","obj_type":"docx","node_type":"origin"}}}`)
+		case strings.HasSuffix(r.URL.Path, "/nodes"):
+			parent := r.URL.Query().Get("parent_node_token")
+			mu.Lock()
+			requested = append(requested, parent)
+			mu.Unlock()
+			items := children[parent]
+			for _, item := range items {
+				item["space_id"] = "space"
+				item["parent_node_token"] = parent
+			}
+			data, _ := json.Marshal(items)
+			fmt.Fprintf(w, `{"code":0,"msg":"ok","data":{"items":%s,"has_more":false}}`, data)
+		case strings.HasSuffix(r.URL.Path, "/spaces/space"):
+			fmt.Fprint(w, `{"code":0,"msg":"ok","data":{"space":{"space_id":"space","name":"Team"}}}`)
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	return server, &requested
+}
+
+func docTitles(nodes []*DocNode) []string {
+	var titles []string
+	for _, node := range nodes {
+		titles = append(titles, node.Title)
+		for _, child := range docTitles(node.Children) {
+			titles = append(titles, node.Title+"/"+child)
+		}
+	}
+	return titles
+}
+
+func TestGetWikiDocsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	server, requested := newWikiDocsServer(t)
+	defer server.Close()
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output")
+	assert.NoError(t, initConfig(filepath.Join(dir, "config.json"), configOverrides{
+		AppID:      "wiki-docs-test",
+		AppSecret:  "secret",
+		BaseURL:    server.URL,
+		OutputPath: output,
+	}))
+	router := setupRouter("", "test-token", nil)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/wiki/docs?url="+url.QueryEscape("https://wiki.test/wiki/root")+query, nil)
+		req.Header.Set("X-API-Key", "test-token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// max_depth=2 时只展开到第二层，快捷方式不展开
+	w := get("&max_depth=2")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response WikiDocsTreeResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, response.Success)
+	assert.Equal(t, "Team", response.Tree.Title)
+	assert.Equal(t, []string{"A", "A/A1", "Link"}, docTitles(response.Tree.Children))
+	assert.ElementsMatch(t, []string{"", "A"}, *requested)
+	assert.FileExists(t, filepath.Join(output, "Team_文档树.md"))
+
+	// 不限制深度时展开所有节点，快捷方式仍然不展开
+	*requested = nil
+	w = get("&stream=true")
+	assert.Equal(t, http.StatusOK, w.Code)
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Len(t, lines, 5) // 4 个节点和最终结果
+	var done struct {
+		Event  string               `json:"event"`
+		Result WikiDocsTreeResponse `json:"result"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &done))
+	assert.Equal(t, "done", done.Event)
+	assert.Equal(t, []string{"A", "A/A1", "A/A1/A2", "Link"}, docTitles(done.Result.Tree.Children))
+	assert.ElementsMatch(t, []string{"", "A", "A1"}, *requested)
+
+	// 没有访问令牌时拒绝请求
+	req := httptest.NewRequest(http.MethodGet, "/wiki/docs?url=x", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/gin-gonic/gin"
+)
+
+// 根据飞书接口的错误分类返回对应的 HTTP 状态码和处理建议
+func apiErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, core.ErrNotFound):
+		return http.StatusNotFound, "文档不存在或已被删除，请检查链接"
+	case errors.Is(err, core.ErrPermissionDenied):
+		return http.StatusForbidden, "没有访问权限，请将应用添加为文档协作者，或登录有权限的用户"
+	case errors.Is(err, core.ErrInvalidCredentials):
+		return http.StatusUnauthorized, "App ID / App Secret 无效或登录已过期，请检查配置或重新登录"
+	case errors.Is(err, core.ErrRateLimited):
+		return http.StatusTooManyRequests, "请求过于频繁，请稍后重试"
+	case errors.Is(err, core.ErrTransient):
+		return http.StatusServiceUnavailable, "飞书服务暂时不可用，请稍后重试"
+	}
+	return http.StatusInternalServerError, ""
+}
+
+// 返回飞书接口调用失败的响应，message 说明失败的操作
+func respondAPIError(c *gin.Context, message string, err error) {
+	status, hint := apiErrorStatus(err)
+	if retryAfter := core.RetryAfter(err); retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	body := gin.H{
+		"success": false,
+		"message": message,
+		"error":   err.Error(),
+	}
+	if hint != "" {
+		body["message"] = message + ": " + hint
+	}
+	c.JSON(status, body)
+}
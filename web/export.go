@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/88250/lute"
+	"github.com/Wsine/feishu2md/core"
+	"github.com/gin-gonic/gin"
+)
+
+// 新版文档的导出结果
+const (
+	exportStatusExported = "exported" // 重新导出
+	exportStatusSkipped  = "skipped"  // 版本没有变化，跳过
+	exportStatusMoved    = "moved"    // 版本没有变化，文档重命名或移动后移动了已导出的文件
+)
+
+// 导出一个新版文档的选项
+type docxExportOptions struct {
+	Dir          string // Markdown 文件所在目录
+	NodeToken    string // 文档所在的知识库节点
+	Index        int    // 在同级节点中的位置，用于文件名模板中的 {index}
+	Namer        *fileNamer
+	Space        string // 文档所在的知识空间，同步知识库时用于找出已删除的文档
+	Force        bool   // 忽略同步状态，总是重新导出
+	Concurrency  int
+	Image        core.ImageOptions
+	SinkType     string
+	StoreDir     string
+	DocumentJSON bool
+}
+
+type docxExportResult struct {
+	Title       string
+	Status      string
+	Path        string
+	JSONPath    string
+	AssetErrors []gin.H
+}
+
+// 导出新版文档及其图片，state 不为空时版本没有变化的文档不再重新导出
+func exportDocx(ctx context.Context, client *core.Client, config *core.Config, state *core.SyncState, docToken string, options docxExportOptions) (*docxExportResult, error) {
+	log.Printf("开始获取文档内容: token=%s", docToken)
+	docx, err := client.GetDocxDocument(ctx, docToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按照文件名模板生成合法且不与其他文档重名的文件名
+	target := exportTarget{Dir: options.Dir, NodeToken: options.NodeToken, Index: options.Index, Namer: options.Namer}
+	if target.Namer == nil {
+		target.Namer = newFileNamer(config.Output.Filename, state)
+	}
+	result := &docxExportResult{Title: docx.Title, Path: target.file(".md", docx.Title, docToken)}
+	docTitle := strings.TrimSuffix(filepath.Base(result.Path), ".md")
+
+	if state != nil && !options.Force && state.Unchanged(docToken, docx.RevisionID) {
+		previous, _ := state.Lookup(docToken)
+		relocated, err := state.Relocate(docToken, result.Path)
+		if err != nil {
+			return nil, fmt.Errorf("移动已导出的文档失败: %w", err)
+		}
+		if relocated {
+			result.Status = exportStatusSkipped
+			if current, _ := state.Lookup(docToken); current.Path != previous.Path {
+				result.Status = exportStatusMoved
+				log.Printf("文档没有更新，从 %s 移动到 %s", previous.Path, current.Path)
+			} else {
+				log.Printf("文档没有更新，跳过: %s", result.Path)
+			}
+			return result, nil
+		}
+	}
+
+	blocks, err := client.GetDocxBlocks(ctx, docx.DocumentID)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("成功获取文档内容: 标题=%s, 块数量=%d", docx.Title, len(blocks))
+
+	parser := core.NewParser(config.Output)
+	markdown := parser.ParseDocxContent(docx, blocks)
+
+	record := core.SyncRecord{Space: options.Space, Title: docx.Title, Revision: docx.RevisionID, Path: result.Path}
+	if options.StoreDir != "" && options.SinkType != core.AssetSinkS3 {
+		// 图片保存到共享图片库，已经下载过的图片不再重复下载
+		markdown, result.AssetErrors, err = storeImages(ctx, client, options.StoreDir, docToken, options.Dir, markdown, parser.ImgTokens, options.Concurrency, options.Image)
+		if err != nil {
+			return nil, fmt.Errorf("保存图片到共享图片库失败: %w", err)
+		}
+		record.AssetStore = options.StoreDir
+	} else {
+		// 图片保存到文档专属的图片目录，或者上传到对象存储
+		sink, err := newAssetSink(options.SinkType, config.Output.AssetSink, options.Dir, docToken, docTitle)
+		if err != nil {
+			return nil, fmt.Errorf("创建图片保存位置失败: %w", err)
+		}
+
+		// 并发下载图片，结果按照图片在文档中出现的顺序处理
+		log.Printf("开始处理文档中的图片，共 %d 个，并发数 %d", len(parser.ImgTokens), options.Concurrency)
+		assets := client.DownloadAssets(ctx, parser.ImgTokens, "", options.Concurrency)
+		var names []string
+		markdown, names, result.AssetErrors = sinkImages(ctx, sink, markdown, assets, options.Image)
+		if local, ok := sink.(*core.LocalSink); ok {
+			for _, name := range names {
+				record.Assets = append(record.Assets, filepath.Join(local.Dir, name))
+			}
+		}
+	}
+
+	engine := lute.New(func(l *lute.Lute) {
+		l.RenderOptions.AutoSpace = true
+	})
+	content := engine.FormatStr("md", markdown)
+
+	// 保存Markdown文件到本地
+	log.Printf("保存Markdown文件到: %s", result.Path)
+	if err := os.WriteFile(result.Path, []byte(content), 0644); err != nil {
+		return nil, fmt.Errorf("保存Markdown文件失败: %w", err)
+	}
+
+	// 同时导出结构化的文档树，供索引和检查工具使用
+	if options.DocumentJSON {
+		result.JSONPath = filepath.Join(options.Dir, docTitle+".json")
+		content, err := core.BuildDocument(docx, blocks).ToJSON()
+		if err == nil {
+			err = os.WriteFile(result.JSONPath, content, 0644)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("保存文档树失败: %w", err)
+		}
+		record.Assets = append(record.Assets, result.JSONPath)
+	}
+
+	if state != nil {
+		if err := state.Record(docToken, record); err != nil {
+			return nil, fmt.Errorf("保存同步状态失败: %w", err)
+		}
+	}
+	result.Status = exportStatusExported
+	return result, nil
+}
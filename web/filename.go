@@ -0,0 +1,135 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Wsine/feishu2md/core"
+)
+
+// 在一次导出中按照配置的模板生成文件名，同一目录中重名时依次添加 _2、_3 等后缀
+// 同步状态中记录的文件属于原来的文档，后导出的同名文档使用带后缀的文件名，多次导出的结果保持一致；
+// 没有同步状态时后缀按照导出的顺序分配，调用方需要按照固定的顺序导出同一目录中的文件
+type fileNamer struct {
+	options core.FilenameOptions
+	state   *core.SyncState
+	date    time.Time
+
+	mu      sync.Mutex
+	claimed map[string]string // 小写的文件路径 → 占用的 token
+	counts  map[string]int    // 目录 → 已分配的文件数量，没有指定 {index} 时使用
+}
+
+// 已存在但同步状态中没有记录的文件，不属于任何文档，不能覆盖
+const unknownOwner = "\x00"
+
+func newFileNamer(options core.FilenameOptions, state *core.SyncState) *fileNamer {
+	return &fileNamer{
+		options: options,
+		state:   state,
+		date:    time.Now(),
+		claimed: map[string]string{},
+		counts:  map[string]int{},
+	}
+}
+
+// 按照模板返回文档在 dir 中的文件路径，ext 为包括点的扩展名
+// 导出为目录时 ext 为空，marker 为目录中记录在同步状态里的文件，用来判断目录属于哪个文档
+func (n *fileNamer) file(dir, ext, marker string, fields core.FilenameFields) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.counts[dir]++
+	if fields.Index == 0 {
+		fields.Index = n.counts[dir]
+	}
+	if fields.Date.IsZero() {
+		fields.Date = n.date
+	}
+	return n.claimLocked(dir, n.options.Render(fields), ext, marker, fields.Token)
+}
+
+// 返回以名称命名的子目录，目录名不使用模板，只做清理和转换
+func (n *fileNamer) dir(parent, name, token string) string {
+	if n.options.Slugify {
+		name = core.Slugify(name)
+	}
+	name = core.SanitizeFilename(name, n.options.ByteLimit())
+	if name == "" {
+		name = core.SanitizeFilename(token, n.options.ByteLimit())
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.claimLocked(parent, name, "", "", token)
+}
+
+// 占用 dir 中不使用模板的文件名，用于同一文档导出的多个文件
+func (n *fileNamer) claim(dir, base, ext, token string) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.claimLocked(dir, base, ext, "", token)
+}
+
+// 占用 dir 中的文件名，已被其他 token 占用时添加后缀，调用时需要持有锁
+// 有同步状态时，磁盘上已有但没有记录的文件同样视为被占用；有 marker 的目录按照其中的 marker 文件判断，
+// 其他目录会合并写入，不做检查
+func (n *fileNamer) claimLocked(dir, base, ext, marker, token string) string {
+	for i := 1; ; i++ {
+		path := filepath.Join(dir, core.SuffixFilename(base, i, n.options.ByteLimit())+ext)
+		key := strings.ToLower(path)
+		owner, claimed := n.claimed[key]
+		if probe := ownerProbe(path, ext, marker); !claimed && n.state != nil && probe != "" {
+			owner = n.state.PathOwner(probe)
+			if _, err := os.Stat(probe); owner == "" && err == nil {
+				owner = unknownOwner
+			}
+		}
+		if owner == "" || owner == token {
+			n.claimed[key] = token
+			return path
+		}
+	}
+}
+
+// 判断名称归属时检查的文件，不需要检查时返回空字符串
+func ownerProbe(path, ext, marker string) string {
+	switch {
+	case marker != "":
+		return filepath.Join(path, marker)
+	case ext != "":
+		return path
+	default:
+		return ""
+	}
+}
+
+// 导出文件的目录和命名方式
+type exportTarget struct {
+	Dir       string
+	NodeToken string // 知识库节点的 token，用于文件名模板中的 {node_token}
+	Index     int    // 在同级节点中的位置，从 1 开始，为 0 时按照导出顺序编号
+	Namer     *fileNamer
+}
+
+// 返回文档在目标目录中的文件路径
+func (t exportTarget) file(ext, title, token string) string {
+	return t.Namer.file(t.Dir, ext, "", core.FilenameFields{Title: title, Token: token, NodeToken: t.NodeToken, Index: t.Index})
+}
+
+// 返回文档导出为目录时的路径，marker 为目录中第一个导出的文件
+func (t exportTarget) directory(marker, title, token string) string {
+	return t.Namer.file(t.Dir, "", marker, core.FilenameFields{Title: title, Token: token, NodeToken: t.NodeToken, Index: t.Index})
+}
+
+// 将导出的文件写入同步状态，第一个文件决定文件名的归属，再次导出时文件名仍然属于这个文档
+func (t exportTarget) record(token, title string, filePaths []string) {
+	if t.Namer.state == nil || len(filePaths) == 0 {
+		return
+	}
+	if err := t.Namer.state.Record(token, core.SyncRecord{Title: title, Path: filePaths[0], Assets: filePaths[1:]}); err != nil {
+		log.Printf("保存同步状态失败: %s", err)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileNamerOwnership(t *testing.T) {
+	dir := t.TempDir()
+	state, err := core.OpenSyncState(dir)
+	assert.NoError(t, err)
+
+	// 第一个多维表格导出后记录在同步状态中
+	first := exportTarget{Dir: dir, Namer: newFileNamer(core.FilenameOptions{}, state)}
+	bitableDir := first.directory(bitableSchemaFile, "Data", "base1")
+	assert.Equal(t, filepath.Join(dir, "Data"), bitableDir)
+	assert.NoError(t, os.MkdirAll(bitableDir, 0o755))
+	schema := filepath.Join(bitableDir, bitableSchemaFile)
+	assert.NoError(t, os.WriteFile(schema, []byte("{}"), 0o644))
+	first.record("base1", "Data", []string{schema})
+
+	// 之后的导出中，同名的其他多维表格使用带后缀的目录，原来的多维表格仍然使用原来的目录
+	next := exportTarget{Dir: dir, Namer: newFileNamer(core.FilenameOptions{}, state)}
+	assert.Equal(t, filepath.Join(dir, "Data_2"), next.directory(bitableSchemaFile, "Data", "base2"))
+	next = exportTarget{Dir: dir, Namer: newFileNamer(core.FilenameOptions{}, state)}
+	assert.Equal(t, bitableDir, next.directory(bitableSchemaFile, "Data", "base1"))
+
+	// 已有但没有记录的文件不会被覆盖
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "Sheet.md"), []byte("notes"), 0o644))
+	assert.Equal(t, filepath.Join(dir, "Sheet_2.md"), next.file(".md", "Sheet", "sheet1"))
+
+	// 没有同步状态时只在本次导出中避免重名
+	plain := exportTarget{Dir: dir, Namer: newFileNamer(core.FilenameOptions{}, nil)}
+	assert.Equal(t, filepath.Join(dir, "Sheet.md"), plain.file(".md", "Sheet", "sheet1"))
+	assert.Equal(t, filepath.Join(dir, "Sheet_2.md"), plain.file(".md", "Sheet", "sheet2"))
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/Wsine/feishu2md/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// 导出云空间文件夹的选项
+type folderExportOptions struct {
+	Docx        docxExportOptions
+	SheetFormat string
+}
+
+// 导出云空间文件夹的结果
+type folderExportResult struct {
+	Exported []string `json:"exported"` // 生成的文件
+	Skipped  []gin.H  `json:"skipped"`  // 不支持导出或没有更新的文件
+	Failures []gin.H  `json:"failures"`
+}
+
+type folderExporter struct {
+	client  *core.Client
+	config  *core.Config
+	state   *core.SyncState
+	options folderExportOptions
+	result  *folderExportResult
+}
+
+// 递归导出文件夹中的文件，子文件夹导出到同名的子目录中，保持与云空间相同的目录结构
+func exportFolder(ctx context.Context, client *core.Client, config *core.Config, folderToken, outputPath string, options folderExportOptions) (*folderExportResult, error) {
+	log.Printf("开始获取文件夹内容: token=%s", folderToken)
+	tree, err := client.GetDriveFolderTree(ctx, folderToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(outputPath, 0755); err != nil {
+		return nil, fmt.Errorf("创建输出目录失败: %w", err)
+	}
+	// 新版文档版本没有变化时不再重新导出
+	state, err := core.OpenSyncState(outputPath)
+	if err != nil {
+		log.Printf("读取同步状态失败，重新导出所有文档: %s", err)
+		state = nil
+	}
+
+	options.Docx.Namer = newFileNamer(config.Output.Filename, state)
+	e := &folderExporter{client: client, config: config, state: state, options: options, result: &folderExportResult{}}
+	e.walk(ctx, tree, outputPath)
+	return e.result, nil
+}
+
+// 云空间不保证文件列表的顺序，同级文件按照名称和 token 排序后导出，使重名后缀和 {index} 保持稳定
+func (e *folderExporter) walk(ctx context.Context, nodes []*core.DriveTreeNode, dir string) {
+	nodes = slices.Clone(nodes)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Name != nodes[j].Name {
+			return nodes[i].Name < nodes[j].Name
+		}
+		return nodes[i].Token < nodes[j].Token
+	})
+	for i, node := range nodes {
+		if node.Type == core.DriveFileTypeFolder {
+			switch {
+			case node.Err != nil:
+				e.fail(node.DriveFile, fmt.Errorf("获取文件夹内容失败: %w", node.Err))
+			case node.Shortcut:
+				e.skip(node.DriveFile, "指向文件夹的快捷方式")
+			default:
+				e.walk(ctx, node.Children, e.options.Docx.Namer.dir(dir, node.Name, node.Token))
+			}
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			e.fail(node.DriveFile, err)
+			continue
+		}
+		if err := e.export(ctx, node.DriveFile, dir, i+1); err != nil {
+			e.fail(node.DriveFile, err)
+		}
+	}
+}
+
+// 按照文件类型导出单个文件，index 为文件在同级文件中的位置
+func (e *folderExporter) export(ctx context.Context, file *core.DriveFile, dir string, index int) error {
+	log.Printf("导出文件: %s (%s)", file.Name, file.Type)
+	target := exportTarget{Dir: dir, Index: index, Namer: e.options.Docx.Namer}
+	switch file.Type {
+	case core.DriveFileTypeDocx:
+		options := e.options.Docx
+		options.Dir = dir
+		options.Index = index
+		result, err := exportDocx(ctx, e.client, e.config, e.state, file.Token, options)
+		if err != nil {
+			return err
+		}
+		if result.Status != exportStatusExported {
+			e.skip(file, "文档没有更新")
+			return nil
+		}
+		e.result.Exported = append(e.result.Exported, result.Path)
+		if result.JSONPath != "" {
+			e.result.Exported = append(e.result.Exported, result.JSONPath)
+		}
+	case core.DriveFileTypeDoc:
+		e.skip(file, "不支持导出旧版文档，请先在飞书中升级为新版文档")
+	case core.DriveFileTypeSheet:
+		filePaths, err := exportSheet(ctx, e.client, file.Token, target, e.options.SheetFormat)
+		if err != nil {
+			return err
+		}
+		e.result.Exported = append(e.result.Exported, filePaths...)
+	case core.DriveFileTypeBitable:
+		_, filePaths, err := exportBitable(ctx, e.client, file.Token, target, e.options.Docx.Concurrency)
+		if err != nil {
+			return err
+		}
+		e.result.Exported = append(e.result.Exported, filePaths...)
+	case core.DriveFileTypeFile:
+		filename, data, err := e.client.DownloadDriveFile(ctx, file.Token)
+		if err != nil {
+			return err
+		}
+		if filename == "" {
+			filename = file.Name
+		}
+		ext := core.SanitizeFilename(filepath.Ext(filename), 0)
+		filePath := target.file(ext, strings.TrimSuffix(filename, ext), file.Token)
+		if err := os.WriteFile(filePath, data, 0644); err != nil {
+			return fmt.Errorf("保存文件失败: %w", err)
+		}
+		target.record(file.Token, file.Name, []string{filePath})
+		e.result.Exported = append(e.result.Exported, filePath)
+	default:
+		e.skip(file, fmt.Sprintf("不支持的文件类型: %s", file.Type))
+	}
+	return nil
+}
+
+func (e *folderExporter) skip(file *core.DriveFile, reason string) {
+	log.Printf("跳过文件 %s: %s", file.Name, reason)
+	e.result.Skipped = append(e.result.Skipped, gin.H{"token": file.Token, "name": file.Name, "type": file.Type, "reason": reason})
+}
+
+func (e *folderExporter) fail(file *core.DriveFile, err error) {
+	log.Printf("导出文件 %s 失败: %s", file.Name, err)
+	e.result.Failures = append(e.result.Failures, gin.H{"token": file.Token, "name": file.Name, "type": file.Type, "error": err.Error()})
+}
+
+// 读取导出文件夹的选项，查询参数覆盖配置文件中的设置
+func folderOptions(c *gin.Context, config *core.Config) (folderExportOptions, error) {
+	imageOpts, err := imageOptions(c, config)
+	if err != nil {
+		return folderExportOptions{}, err
+	}
+	return folderExportOptions{
+		Docx: docxExportOptions{
+			Force:        c.Query("force") == "true",
+			Concurrency:  assetConcurrency(c, config),
+			Image:        imageOpts,
+			SinkType:     c.DefaultQuery("asset_sink", config.Output.AssetSink.Type),
+			DocumentJSON: c.DefaultQuery("document_json", strconv.FormatBool(config.Output.DocumentJSON)) == "true",
+		},
+		SheetFormat: c.DefaultQuery("sheet_format", config.Output.SheetFormat),
+	}, nil
+}
+
+// 递归导出云空间文件夹的处理函数
+func exportFolderHandler(c *gin.Context) {
+	folderURL, err := url.QueryUnescape(c.Query("url"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的文件夹URL"})
+		return
+	}
+	folderToken, err := utils.ValidateFolderURL(folderURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": fmt.Sprintf("无效的文件夹URL: %s", err)})
+		return
+	}
+	config, ok := requestConfig(c, folderURL)
+	if !ok {
+		return
+	}
+	outputPath, ok := outputDir(c, outputPathParam(c, config))
+	if !ok {
+		return
+	}
+	options, err := folderOptions(c, config)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": fmt.Sprintf("导出参数无效: %s", err)})
+		return
+	}
+	if options.Docx.StoreDir, ok = assetStoreParam(c, config); !ok {
+		return
+	}
+	client := newClient(config, folderURL)
+	result, err := exportFolder(context.Background(), client, config, folderToken, outputPath, options)
+	if err != nil {
+		log.Printf("导出文件夹失败: %s", err)
+		respondAPIError(c, "导出文件夹失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  len(result.Failures) == 0,
+		"message":  fmt.Sprintf("文件夹导出完成: 导出 %d 个文件，跳过 %d 个，失败 %d 个", len(result.Exported), len(result.Skipped), len(result.Failures)),
+		"exported": result.Exported,
+		"skipped":  result.Skipped,
+		"failures": result.Failures,
+	})
+}
+
+// 命令行导出文件夹，使用配置中的应用凭证、输出目录和导出选项
+func runFolderExport(folderURL string) error {
+	folderToken, err := utils.ValidateFolderURL(folderURL)
+	if err != nil {
+		return err
+	}
+	config, err := loadProfileConfig("", folderURL)
+	if err != nil {
+		return err
+	}
+	options := folderExportOptions{
+		Docx: docxExportOptions{
+			Concurrency:  config.Output.AssetConcurrency,
+			Image:        config.Output.Image,
+			SinkType:     config.Output.AssetSink.Type,
+			StoreDir:     config.Output.AssetStoreDir,
+			DocumentJSON: config.Output.DocumentJSON,
+		},
+		SheetFormat: config.Output.SheetFormat,
+	}
+	client := newClient(config, folderURL)
+	result, err := exportFolder(context.Background(), client, config, folderToken, configOutputPath(config), options)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("导出 %d 个文件，跳过 %d 个，失败 %d 个\n", len(result.Exported), len(result.Skipped), len(result.Failures))
+	for _, failure := range result.Failures {
+		fmt.Printf("  %s: %s\n", failure["name"], failure["error"])
+	}
+	if len(result.Failures) > 0 {
+		return fmt.Errorf("%d 个文件导出失败", len(result.Failures))
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/gin-gonic/gin"
+)
+
+// 将本地 Markdown 文件或直接提交的 Markdown 内容导入为飞书文档
+func importHandler(c *gin.Context) {
+	var request struct {
+		FilePath            string `json:"file_path"`
+		Markdown            string `json:"markdown"`
+		BaseDir             string `json:"base_dir"`
+		Title               string `json:"title"`
+		FolderToken         string `json:"folder_token"`
+		WikiSpaceID         string `json:"wiki_space_id"`
+		WikiParentNodeToken string `json:"wiki_parent_node_token"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数",
+		})
+		return
+	}
+
+	// 只能读取允许导入的目录中的文件，图片目录同样需要位于其中
+	roots := importRoots()
+	markdown := request.Markdown
+	baseDir := ""
+	if request.BaseDir != "" {
+		dir, err := checkOutputPath(request.BaseDir, roots)
+		if err != nil {
+			respondPathError(c, "检查图片目录失败", err)
+			return
+		}
+		baseDir = dir
+	}
+	if request.FilePath != "" {
+		filePath, err := checkOutputPath(request.FilePath, roots)
+		if err != nil {
+			respondPathError(c, "检查 Markdown 文件路径失败", err)
+			return
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": fmt.Sprintf("读取 Markdown 文件失败: %s", err),
+			})
+			return
+		}
+		markdown = string(data)
+		if baseDir == "" {
+			baseDir = filepath.Dir(filePath)
+		}
+	}
+	if markdown == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "缺少 Markdown 内容",
+		})
+		return
+	}
+
+	config, ok := requestConfig(c, "")
+	if !ok {
+		return
+	}
+	client := newClient(config, "")
+	ctx := context.Background()
+
+	log.Printf("开始导入 Markdown: 文件=%s, 文件夹=%s, 知识空间=%s, 父节点=%s",
+		request.FilePath, request.FolderToken, request.WikiSpaceID, request.WikiParentNodeToken)
+	result, err := client.ImportMarkdown(ctx, markdown, core.ImportOptions{
+		Title:               request.Title,
+		FolderToken:         request.FolderToken,
+		WikiSpaceID:         request.WikiSpaceID,
+		WikiParentNodeToken: request.WikiParentNodeToken,
+		BaseDir:             baseDir,
+	})
+	if err != nil {
+		log.Printf("导入 Markdown 失败: %s", err)
+		respondAPIError(c, "导入 Markdown 失败", err)
+		return
+	}
+
+	docURL := client.DocumentURL("docx", result.DocumentID)
+	if result.NodeToken != "" {
+		docURL = client.DocumentURL("wiki", result.NodeToken)
+	}
+	log.Printf("导入 Markdown 成功: %s", docURL)
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"message":     "导入成功",
+		"document_id": result.DocumentID,
+		"node_token":  result.NodeToken,
+		"title":       result.Title,
+		"url":         docURL,
+	})
+}
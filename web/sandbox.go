@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/gin-gonic/gin"
+)
+
+// 请求中的路径不在允许的输出目录中
+type outsideRootError struct {
+	Path     string
+	Resolved string
+	Roots    []string
+}
+
+func (e *outsideRootError) Error() string {
+	return fmt.Sprintf("输出路径 %s 不在允许的目录中: %s", e.Path, strings.Join(e.Roots, ", "))
+}
+
+// 允许写入的目录，未在配置中设置 server.output_roots 时为启动时全局配置和各命名配置的输出目录和共享图片目录
+// 通过 /config 修改的输出目录不会加入允许的目录，否则持有令牌的请求可以把输出目录改为任意路径
+func outputRoots() []string {
+	if roots := loadConfig().Server.OutputRoots; len(roots) > 0 {
+		return roots
+	}
+	serverConfig.mu.RLock()
+	defer serverConfig.mu.RUnlock()
+	return serverConfig.roots
+}
+
+// 按照当前配置计算默认的允许目录，只在读取配置文件时调用
+func defaultOutputRoots() []string {
+	config := loadConfig()
+	roots := []string{configOutputPath(config)}
+	if config.Output.AssetStoreDir != "" {
+		roots = append(roots, config.Output.AssetStoreDir)
+	}
+	for _, name := range config.ProfileNames() {
+		profile, err := loadProfileConfig(name, "")
+		if err != nil {
+			continue
+		}
+		roots = append(roots, configOutputPath(profile))
+		if profile.Output.AssetStoreDir != "" {
+			roots = append(roots, profile.Output.AssetStoreDir)
+		}
+	}
+	return roots
+}
+
+// 导入接口允许读取的目录，未在配置中设置 server.import_roots 时与允许写入的目录相同
+func importRoots() []string {
+	if roots := loadConfig().Server.ImportRoots; len(roots) > 0 {
+		return roots
+	}
+	return outputRoots()
+}
+
+// 返回解析符号链接后的绝对路径，路径不存在时解析最深的已存在的上级目录
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	existing, rest := abs, ""
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			return filepath.Join(resolved, rest), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return abs, nil
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+}
+
+// 检查路径是否位于允许的目录中，返回解析后的绝对路径
+func checkOutputPath(path string, roots []string) (string, error) {
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	for _, root := range roots {
+		resolvedRoot, err := resolvePath(root)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(resolvedRoot, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", &outsideRootError{Path: path, Resolved: resolved, Roots: roots}
+}
+
+// 检查请求中的输出路径，不在允许的目录中时返回 403 并写入审计日志
+func sandboxPath(c *gin.Context, path string) (string, bool) {
+	resolved, err := checkOutputPath(path, outputRoots())
+	if err != nil {
+		respondPathError(c, "检查输出路径失败", err)
+		return "", false
+	}
+	return resolved, true
+}
+
+// 共享图片目录参数，查询参数覆盖配置中的设置，目录需要位于允许写入的目录中
+func assetStoreParam(c *gin.Context, config *core.Config) (string, bool) {
+	dir := c.DefaultQuery("asset_store", config.Output.AssetStoreDir)
+	if dir == "" {
+		return "", true
+	}
+	return sandboxPath(c, dir)
+}
+
+// 根据自定义路径参数构建输出目录并检查，失败时返回错误响应
+func outputDir(c *gin.Context, outputPath string) (string, bool) {
+	dir, err := resolveOutputPath(outputPath, c.Query("path"))
+	if err != nil {
+		respondPathError(c, "创建自定义路径目录失败", err)
+		return "", false
+	}
+	return dir, true
+}
+
+// 路径越界时返回 403，其他错误返回 500
+func respondPathError(c *gin.Context, message string, err error) {
+	var outside *outsideRootError
+	if errors.As(err, &outside) {
+		auditPathDenied(c, outside)
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": outside.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": fmt.Sprintf("%s: %s", message, err)})
+}
+
+// 审计日志，每行一条 JSON 记录
+var auditLog = struct {
+	sync.Mutex
+	file *os.File
+}{}
+
+// 打开审计日志文件，path 为空时使用日志目录下的 audit.log
+func initAuditLog(path string) error {
+	if path == "" {
+		dir, err := logDir()
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(dir, "audit.log")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	auditLog.Lock()
+	defer auditLog.Unlock()
+	auditLog.file = file
+	log.Printf("审计日志: %s", path)
+	return nil
+}
+
+// 记录被拒绝的路径，审计日志未打开时只写入普通日志
+func auditPathDenied(c *gin.Context, err *outsideRootError) {
+	log.Printf("拒绝写入允许目录之外的路径: %s (%s)，来源 %s %s", err.Path, err.Resolved, c.ClientIP(), c.Request.URL.Path)
+	record, _ := json.Marshal(map[string]any{
+		"time":     time.Now().Format(time.RFC3339),
+		"event":    "output_path_denied",
+		"client":   c.ClientIP(),
+		"method":   c.Request.Method,
+		"endpoint": c.Request.URL.Path,
+		"path":     err.Path,
+		"resolved": err.Resolved,
+		"roots":    err.Roots,
+	})
+	auditLog.Lock()
+	defer auditLog.Unlock()
+	if auditLog.file != nil {
+		auditLog.file.Write(append(record, '\n'))
+	}
+}
+
+// 保存配置时检查输出目录和共享图片目录，需要位于允许写入的目录中
+func checkConfigOutputPaths(c *gin.Context, output *core.OutputConfig) bool {
+	roots := outputRoots()
+	for _, path := range []string{output.OutputPath, output.AssetStoreDir} {
+		if path == "" {
+			continue
+		}
+		if _, err := checkOutputPath(path, roots); err != nil {
+			respondPathError(c, "检查输出路径失败", err)
+			return false
+		}
+	}
+	return true
+}
@@ -1,29 +1,56 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Wsine/feishu2md/core"
+	"github.com/Wsine/feishu2md/utils"
 	"github.com/gin-gonic/gin"
 )
 
-// 配置信息结构
-type ConfigInfo struct {
-	AppID      string `json:"app_id"`
-	AppSecret  string `json:"app_secret"`
-	OutputPath string `json:"output_path"`
+// 获取客户端，能从文档链接识别出域名时优先使用链接所在的站点
+// 相同凭证和站点的请求共享同一个客户端，以复用访问令牌和限流器
+func newClient(config *core.Config, rawURL string) *core.Client {
+	feishu := config.Feishu
+	if domain, baseURL := utils.ParseDomain(rawURL); domain != "" {
+		feishu.Domain = domain
+		if baseURL != "" {
+			feishu.BaseURL = baseURL
+		}
+	}
+	options := feishu.ClientOptions()
+	// 用户登录后以用户身份访问文档，未登录时使用应用身份
+	if store, err := userTokenStore(config.ProfileName); err == nil {
+		options = append(options, core.WithUserTokenStore(store))
+	}
+	return core.GetClient(feishu.AppId, feishu.AppSecret, options...)
+}
+
+// 日志目录，与配置文件位于同一个用户配置目录下
+func logDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户配置目录失败: %w", err)
+	}
+	return filepath.Join(configDir, "feishu2md", "logs"), nil
 }
 
 // 初始化日志系统，将日志输出到文件
 func initLogger() (*os.File, error) {
 	// 创建日志目录
-	logDir := filepath.Join(os.Getenv("APPDATA"), "feishu2md", "logs")
+	logDir, err := logDir()
+	if err != nil {
+		return nil, err
+	}
 	if err := os.MkdirAll(logDir, 0755); err != nil {
 		return nil, fmt.Errorf("创建日志目录失败: %w", err)
 	}
@@ -52,11 +79,73 @@ func main() {
 	// 解析命令行参数
 	var port int
 	var logToFile bool
+	var login bool
+	var encryptConfig bool
+	var folderURL string
+	var webDir string
+	var configPath string
+	var flags configOverrides
+	var server core.ServerConfig
+	var allowedOrigins string
+	var noAuth bool
+	var outputRoots string
+	var importRoots string
 
 	flag.IntVar(&port, "port", 8080, "服务器端口")
 	flag.BoolVar(&logToFile, "log-to-file", false, "是否将日志输出到文件")
+	flag.BoolVar(&login, "login", false, "以用户身份登录飞书后退出，用于访问未共享给应用的文档")
+	flag.StringVar(&folderURL, "export-folder", "", "递归导出云空间文件夹的链接，导出完成后退出")
+	flag.StringVar(&flags.OutputPath, "output", "", "输出目录，优先于配置文件和 FEISHU_OUTPUT_PATH 环境变量")
+	flag.StringVar(&webDir, "web-dir", "", "网页界面的静态文件目录，为空时使用内置的页面")
+	flag.StringVar(&configPath, "config", "", "配置文件路径，为空时使用用户配置目录下的 feishu2md/config.json")
+	flag.StringVar(&flags.AppID, "app-id", "", "应用的 App ID，优先于配置文件和 FEISHU_APP_ID 环境变量")
+	flag.StringVar(&flags.AppSecret, "app-secret", "", "应用的 App Secret，优先于配置文件和 FEISHU_APP_SECRET 环境变量")
+	flag.StringVar(&flags.BaseURL, "base-url", "", "开放平台接口地址，优先于配置文件和 FEISHU_BASE_URL 环境变量")
+	flag.StringVar(&flags.Domain, "domain", "", "文档链接的域名，优先于配置文件和 FEISHU_DOMAIN 环境变量")
+	flag.BoolVar(&encryptConfig, "encrypt-config", false, "使用 "+core.PassphraseEnv+" 提供的口令加密配置文件中的明文密钥后退出")
+	flag.StringVar(&flags.Profile, "profile", "", "使用配置文件中的命名配置，为空时根据文档链接的域名选择")
+	flag.StringVar(&server.Host, "host", "", "监听的地址，默认只监听本机的 "+defaultServerHost+"，局域网访问时可设为 0.0.0.0")
+	flag.StringVar(&allowedOrigins, "allowed-origins", "", "允许跨域访问的网页来源，多个来源用逗号分隔")
+	flag.BoolVar(&noAuth, "no-auth", false, "关闭访问令牌校验，只能在监听本机地址时使用")
+	flag.StringVar(&outputRoots, "output-roots", "", "接口允许写入的目录，多个目录用逗号分隔，默认为配置中的输出目录")
+	flag.StringVar(&importRoots, "import-roots", "", "导入接口允许读取的目录，多个目录用逗号分隔，默认与允许写入的目录相同")
+	flag.StringVar(&server.AuditLog, "audit-log", "", "审计日志文件，默认为日志目录下的 audit.log")
+	flag.StringVar(&server.TLSCertFile, "tls-cert", "", "HTTPS 证书文件")
+	flag.StringVar(&server.TLSKeyFile, "tls-key", "", "HTTPS 私钥文件")
+	flag.StringVar(&server.PublicURL, "public-url", "", "浏览器访问服务的地址，用于生成飞书授权回调地址，例如 https://feishu2md.example.com")
 	flag.Parse()
 
+	if outputRoots != "" {
+		flags.OutputRoots = strings.Split(outputRoots, ",")
+	}
+	if importRoots != "" {
+		flags.ImportRoots = strings.Split(importRoots, ",")
+	}
+	if err := initConfig(configPath, envOverrides().merge(flags)); err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	if encryptConfig {
+		if err := runEncryptConfig(); err != nil {
+			log.Fatalf("加密配置文件失败: %v", err)
+		}
+		return
+	}
+
+	if login {
+		if err := runLogin(port); err != nil {
+			log.Fatalf("登录失败: %v", err)
+		}
+		return
+	}
+
+	if folderURL != "" {
+		if err := runFolderExport(folderURL); err != nil {
+			log.Fatalf("导出文件夹失败: %v", err)
+		}
+		return
+	}
+
 	// 设置日志
 	if logToFile {
 		// 初始化日志系统
@@ -74,119 +163,112 @@ func main() {
 
 	log.Printf("后端服务启动中...")
 
-	// 创建路由
-	router := setupRouter()
-
-	// 启动服务器
-	log.Printf("服务器启动在 http://localhost:%d", port)
-	if err := router.Run(fmt.Sprintf(":%d", port)); err != nil {
-		log.Fatalf("启动服务器失败: %v", err)
+	// 命令行参数优先于配置文件中的服务设置
+	settings := loadConfig().Server
+	settings.Host = firstNonEmpty(server.Host, settings.Host, defaultServerHost)
+	settings.TLSCertFile = firstNonEmpty(server.TLSCertFile, settings.TLSCertFile)
+	settings.TLSKeyFile = firstNonEmpty(server.TLSKeyFile, settings.TLSKeyFile)
+	settings.DisableAuth = settings.DisableAuth || noAuth
+	if allowedOrigins != "" {
+		settings.AllowedOrigins = strings.Split(allowedOrigins, ",")
 	}
-}
-
-// 保存配置处理函数
-func saveConfigHandler(c *gin.Context) {
-	var config ConfigInfo
-	if err := c.ShouldBindJSON(&config); err != nil {
-		c.JSON(400, gin.H{"success": false, "message": "无效的配置数据"})
-		return
+	settings.AuditLog = firstNonEmpty(server.AuditLog, settings.AuditLog)
+	settings.PublicURL = firstNonEmpty(server.PublicURL, settings.PublicURL)
+	if err := initAuditLog(settings.AuditLog); err != nil {
+		log.Printf("警告: 打开审计日志失败: %v", err)
 	}
+	useTLS := settings.TLSCertFile != "" && settings.TLSKeyFile != ""
 
-	// 创建配置目录
-	configDir := filepath.Join(os.Getenv("APPDATA"), "feishu2md")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		c.JSON(500, gin.H{"success": false, "message": "创建配置目录失败"})
-		return
+	var token string
+	if settings.DisableAuth {
+		if !isLoopbackHost(settings.Host) {
+			log.Fatalf("监听非本机地址 %s 时不能关闭访问令牌校验", settings.Host)
+		}
+		log.Printf("警告: 已关闭访问令牌校验，本机的任何程序都可以使用应用凭证访问文档")
+	} else {
+		var tokenPath string
+		var err error
+		token, tokenPath, err = loadServerToken(serverConfig.path)
+		if err != nil {
+			log.Fatalf("读取访问令牌失败: %v", err)
+		}
+		if tokenPath != "" {
+			log.Printf("访问令牌保存在 %s", tokenPath)
+		}
+		if clientPath, err := publishServerToken(token, tokenPath); err != nil {
+			log.Printf("警告: 写入桌面客户端读取的访问令牌失败: %v", err)
+		} else if clientPath != "" {
+			log.Printf("访问令牌已写入桌面客户端读取的 %s", clientPath)
+		}
+	}
+	if !isLoopbackHost(settings.Host) && !useTLS {
+		log.Printf("警告: 未启用 TLS，访问令牌和文档内容将以明文在网络中传输")
 	}
 
-	// 设置环境变量
-	os.Setenv("FEISHU_APP_ID", config.AppID)
-	os.Setenv("FEISHU_APP_SECRET", config.AppSecret)
+	// 创建路由
+	router := setupRouter(webDir, token, settings.AllowedOrigins)
 
-	// 保存配置到文件
-	configFile := filepath.Join(configDir, "config.json")
-	file, err := os.Create(configFile)
-	if err != nil {
-		c.JSON(500, gin.H{"success": false, "message": "创建配置文件失败"})
-		return
+	// 启动服务器
+	addr := net.JoinHostPort(settings.Host, strconv.Itoa(port))
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
 	}
-	defer file.Close()
-
-	// 写入配置
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(config); err != nil {
-		c.JSON(500, gin.H{"success": false, "message": "写入配置失败"})
-		return
+	publicBaseURL = serverBaseURL(settings.PublicURL, scheme, settings.Host, port)
+	log.Printf("服务器启动在 %s://%s，授权回调地址 %s/auth/callback", scheme, addr, publicBaseURL)
+	if token != "" {
+		// 令牌只输出到控制台，不写入日志文件
+		fmt.Printf("网页界面: %s://%s/#token=%s\n", scheme, addr, token)
 	}
-
-	c.JSON(200, gin.H{"success": true, "message": "配置已保存"})
-}
-
-// 获取配置处理函数
-func getConfigHandler(c *gin.Context) {
-	configFile := filepath.Join(os.Getenv("APPDATA"), "feishu2md", "config.json")
-
-	// 检查配置文件是否存在
-	if _, err := os.Stat(configFile); os.IsNotExist(err) {
-		c.JSON(200, gin.H{
-			"success": true,
-			"config": ConfigInfo{
-				AppID:      "",
-				AppSecret:  "",
-				OutputPath: filepath.Join(os.Getenv("USERPROFILE"), "Documents"),
-			},
-		})
-		return
+	var err error
+	if useTLS {
+		err = router.RunTLS(addr, settings.TLSCertFile, settings.TLSKeyFile)
+	} else {
+		err = router.Run(addr)
 	}
-
-	// 读取配置文件
-	data, err := os.ReadFile(configFile)
 	if err != nil {
-		c.JSON(500, gin.H{"success": false, "message": "读取配置文件失败"})
-		return
-	}
-
-	var config ConfigInfo
-	if err := json.Unmarshal(data, &config); err != nil {
-		c.JSON(500, gin.H{"success": false, "message": "解析配置文件失败"})
-		return
+		log.Fatalf("启动服务器失败: %v", err)
 	}
-
-	c.JSON(200, gin.H{
-		"success": true,
-		"config":  config,
-	})
 }
 
-func setupRouter() *gin.Engine {
+// token 为空时不校验访问令牌，allowedOrigins 为允许跨域访问的网页来源
+func setupRouter(webDir, token string, allowedOrigins []string) *gin.Engine {
 	router := gin.New()
 
 	// 设置CORS
-	router.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
+	router.Use(corsMiddleware(allowedOrigins))
+
+	// 浏览器跳转的授权回调不能携带令牌，由登录时生成的 state 校验
+	router.GET("/auth/callback", callbackHandler)
+	// 登录会替换保存的用户令牌，同样需要访问令牌，浏览器打开时使用 /auth/login?token=<令牌>
+	router.GET("/auth/login", queryTokenAuth(token), loginHandler)
 
-		c.Next()
-	})
+	// 其余接口都需要访问令牌
+	api := router.Group("", tokenAuth(token))
 
 	// 注册路由
-	router.GET("/download", downloadHandler)
-	router.GET("/config", getConfigHandler)
-	router.POST("/config", saveConfigHandler)
+	api.GET("/download", downloadHandler)
+	api.GET("/download/zip", downloadZipHandler)
+	api.GET("/config", getConfigHandler)
+	api.POST("/config", saveConfigHandler)
+	api.POST("/import", importHandler)
+	api.GET("/folder/export", exportFolderHandler)
+
+	// 用户登录相关接口
+	api.GET("/auth/status", authStatusHandler)
+	api.POST("/auth/logout", logoutHandler)
 
 	// Wiki相关接口
-	router.GET("/wiki/space-info", getWikiSpaceInfoHandler)
-	router.GET("/wiki/top-nodes", getWikiTopNodesHandler)
-	router.GET("/wiki/node-children", getWikiNodeChildrenHandler)
-	router.POST("/wiki/save-tree", saveWikiTreeHandler)
-	router.GET("/wiki/spaces", getAllWikiSpacesHandler) // 获取所有空间列表的路由
+	api.GET("/wiki/space-info", getWikiSpaceInfoHandler)
+	api.GET("/wiki/docs", getWikiDocsHandler) // 遍历知识库生成文档树，支持 stream、workers 和 max_depth 参数
+	api.GET("/wiki/top-nodes", getWikiTopNodesHandler)
+	api.GET("/wiki/node-children", getWikiNodeChildrenHandler)
+	api.POST("/wiki/save-tree", saveWikiTreeHandler)
+	api.GET("/wiki/spaces", getAllWikiSpacesHandler) // 获取所有空间列表的路由
+	api.GET("/wiki/sync", syncWikiHandler)           // 增量同步知识库中的文档
+
+	// 网页界面
+	registerUI(router, webDir)
 
 	return router
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/gin-gonic/gin"
+)
+
+// 导出电子表格，按照 format 保存为 Markdown、CSV 或 XLSX
+func downloadSheet(c *gin.Context, ctx context.Context, client *core.Client, token string, target exportTarget, format string) {
+	filePaths, err := exportSheet(ctx, client, token, target, format)
+	if err != nil {
+		log.Printf("导出电子表格失败: %s", err)
+		respondAPIError(c, "导出电子表格失败", err)
+		return
+	}
+
+	log.Printf("电子表格导出成功: %v", filePaths)
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "电子表格导出成功",
+		"file_path":  filePaths[0],
+		"file_paths": filePaths,
+	})
+}
+
+// 各导出格式的文件扩展名，CSV 格式按照第一个文件检查重名
+var sheetFormatExt = map[string]string{
+	core.SheetFormatCSV:      ".csv",
+	core.SheetFormatXLSX:     ".xlsx",
+	core.SheetFormatMarkdown: ".md",
+	"":                       ".md",
+}
+
+// 获取电子表格并写入输出目录，返回生成的文件路径
+func exportSheet(ctx context.Context, client *core.Client, token string, target exportTarget, format string) ([]string, error) {
+	log.Printf("开始获取电子表格内容: token=%s, 格式=%s", token, format)
+	spreadsheet, err := client.GetSpreadsheet(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("成功获取电子表格内容: 标题=%s, 工作表数量=%d", spreadsheet.Title, len(spreadsheet.Sheets))
+	// 只含有内嵌多维表格等非表格工作表或空工作表时没有可导出的内容
+	if len(spreadsheet.Sheets) == 0 {
+		return nil, fmt.Errorf("电子表格中没有可导出的工作表")
+	}
+
+	ext := sheetFormatExt[format]
+	if ext == "" {
+		return nil, fmt.Errorf("不支持的电子表格导出格式: %s", format)
+	}
+	title := strings.TrimSuffix(filepath.Base(target.file(ext, spreadsheet.Title, token)), ext)
+	filePaths, err := writeSpreadsheet(spreadsheet, target, title, token, format)
+	if err != nil {
+		return nil, fmt.Errorf("保存电子表格失败: %w", err)
+	}
+	target.record(token, spreadsheet.Title, filePaths)
+	return filePaths, nil
+}
+
+// 将电子表格写入输出目录，返回生成的文件路径
+func writeSpreadsheet(spreadsheet *core.Spreadsheet, target exportTarget, title, token, format string) ([]string, error) {
+	outputPath := target.Dir
+	if err := os.MkdirAll(outputPath, 0755); err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case core.SheetFormatCSV:
+		// 每个工作表一个 CSV 文件，只有一个工作表时直接使用表格标题
+		var filePaths []string
+		used := map[string]bool{}
+		for _, sheet := range spreadsheet.Sheets {
+			filePath := filepath.Join(outputPath, title+".csv")
+			if len(spreadsheet.Sheets) > 1 {
+				// 文件名不与其他文档的文件重名；工作表名称清理后可能相同，同一表格中已使用的文件名继续添加后缀
+				name := title + "_" + core.SanitizeFilename(sheet.Title, 0)
+				for i := 1; ; i++ {
+					filePath = target.Namer.claim(outputPath, core.SuffixFilename(name, i, target.Namer.options.ByteLimit()), ".csv", token)
+					if !used[strings.ToLower(filePath)] {
+						break
+					}
+				}
+			}
+			used[strings.ToLower(filePath)] = true
+			content, err := sheet.ToCSV()
+			if err != nil {
+				return nil, err
+			}
+			if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+				return nil, err
+			}
+			filePaths = append(filePaths, filePath)
+		}
+		if len(filePaths) == 0 {
+			return nil, fmt.Errorf("电子表格中没有可导出的工作表")
+		}
+		return filePaths, nil
+	case core.SheetFormatXLSX:
+		buf := new(bytes.Buffer)
+		if err := core.WriteXLSX(buf, spreadsheet.Sheets); err != nil {
+			return nil, err
+		}
+		filePath := filepath.Join(outputPath, title+".xlsx")
+		if err := os.WriteFile(filePath, buf.Bytes(), 0644); err != nil {
+			return nil, err
+		}
+		return []string{filePath}, nil
+	case core.SheetFormatMarkdown, "":
+		filePath := filepath.Join(outputPath, title+".md")
+		if err := os.WriteFile(filePath, []byte(spreadsheet.ToMarkdown()), 0644); err != nil {
+			return nil, err
+		}
+		return []string{filePath}, nil
+	default:
+		return nil, fmt.Errorf("不支持的电子表格导出格式: %s", format)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteSpreadsheetCSVUniqueNames(t *testing.T) {
+	dir := t.TempDir()
+	state, err := core.OpenSyncState(dir)
+	assert.NoError(t, err)
+	// 其他文件已经占用了第一个工作表的文件名
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "Report_A_B.csv"), []byte("other"), 0o644))
+
+	spreadsheet := &core.Spreadsheet{Title: "Report"}
+	for _, title := range []string{"A/B", "A:B", "A_B_2"} {
+		spreadsheet.Sheets = append(spreadsheet.Sheets, &core.Sheet{Title: title, Rows: [][]core.SheetCell{{{Text: title}}}})
+	}
+	target := exportTarget{Dir: dir, Namer: newFileNamer(core.FilenameOptions{}, state)}
+	filePaths, err := writeSpreadsheet(spreadsheet, target, "Report", "sheet1", core.SheetFormatCSV)
+	assert.NoError(t, err)
+
+	var names []string
+	for _, path := range filePaths {
+		names = append(names, filepath.Base(path))
+	}
+	assert.Equal(t, []string{"Report_A_B_2.csv", "Report_A_B_3.csv", "Report_A_B_2_2.csv"}, names)
+	data, err := os.ReadFile(filepath.Join(dir, "Report_A_B.csv"))
+	assert.NoError(t, err)
+	assert.Equal(t, "other", string(data))
+	for i, path := range filePaths {
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), spreadsheet.Sheets[i].Title)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/Wsine/feishu2md/core"
+	"github.com/Wsine/feishu2md/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// 同步知识库时删除的文档的处理方式
+const (
+	syncDeletedArchive = "archive" // 移动到输出目录下的归档目录
+	syncDeletedRemove  = "remove"  // 直接删除
+	syncDeletedKeep    = "keep"    // 保留文件和记录
+)
+
+// 将知识库中的新版文档增量同步到输出目录
+// 版本没有变化的文档跳过，重命名或移动的文档移动已导出的文件，知识库中已删除的文档按照 deleted 参数归档或删除
+// 文档按照知识库的层级保存，有子节点的文档的子文档保存在与文档同名的目录中
+func syncWikiHandler(c *gin.Context) {
+	wikiURL, err := url.QueryUnescape(c.Query("url"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的知识库URL"})
+		return
+	}
+	docType, nodeToken, err := utils.ValidateDocumentURL(wikiURL)
+	if err != nil || docType != "wiki" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的知识库URL，请确保提供的是知识库链接"})
+		return
+	}
+	deleted := c.DefaultQuery("deleted", syncDeletedArchive)
+	if deleted != syncDeletedArchive && deleted != syncDeletedRemove && deleted != syncDeletedKeep {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": fmt.Sprintf("无效的 deleted 参数: %s", deleted)})
+		return
+	}
+
+	ctx := context.Background()
+	config, ok := requestConfig(c, wikiURL)
+	if !ok {
+		return
+	}
+	outputPath, ok := sandboxPath(c, outputPathParam(c, config))
+	if !ok {
+		return
+	}
+	storeDir, ok := assetStoreParam(c, config)
+	if !ok {
+		return
+	}
+	client := newClient(config, wikiURL)
+	imageOpts, err := imageOptions(c, config)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": fmt.Sprintf("图片处理参数无效: %s", err)})
+		return
+	}
+
+	node, err := client.GetWikiNodeInfo(ctx, nodeToken)
+	if err != nil {
+		respondAPIError(c, "获取知识库信息失败", err)
+		return
+	}
+	topNodes, err := client.ListWikiNodes(ctx, node.SpaceID, "")
+	if err != nil {
+		respondAPIError(c, "获取知识库顶级节点失败", err)
+		return
+	}
+	options := docxExportOptions{
+		Space:        node.SpaceID,
+		Force:        c.Query("force") == "true",
+		Concurrency:  assetConcurrency(c, config),
+		Image:        imageOpts,
+		SinkType:     c.DefaultQuery("asset_sink", config.Output.AssetSink.Type),
+		StoreDir:     storeDir,
+		DocumentJSON: c.DefaultQuery("document_json", strconv.FormatBool(config.Output.DocumentJSON)) == "true",
+	}
+	tree := client.CrawlWikiTree(ctx, topNodes, core.WikiCrawlOptions{Workers: config.Output.CrawlWorkers})
+
+	if err := os.MkdirAll(outputPath, 0755); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": fmt.Sprintf("创建输出目录失败: %s", err)})
+		return
+	}
+	state, err := core.OpenSyncState(outputPath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": fmt.Sprintf("读取同步状态失败: %s", err)})
+		return
+	}
+
+	options.Namer = newFileNamer(config.Output.Filename, state)
+	s := &wikiSync{client: client, config: config, state: state, options: options, keep: map[string]bool{}, counts: map[string]int{}, complete: true}
+	s.walk(ctx, tree, outputPath)
+
+	// 知识库没有完整遍历或有文档导出失败时，无法确定哪些文档已被删除
+	var removed []string
+	if deleted != syncDeletedKeep && s.complete {
+		removed, err = state.Prune(node.SpaceID, s.keep, deleted == syncDeletedArchive)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": fmt.Sprintf("清理已删除的文档失败: %s", err)})
+			return
+		}
+	} else if deleted != syncDeletedKeep {
+		log.Printf("同步不完整，不清理已删除的文档")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  len(s.failures) == 0,
+		"message":  fmt.Sprintf("同步完成: 导出 %d 个，跳过 %d 个，移动 %d 个，删除 %d 个", s.counts[exportStatusExported], s.counts[exportStatusSkipped], s.counts[exportStatusMoved], len(removed)),
+		"exported": s.counts[exportStatusExported],
+		"skipped":  s.counts[exportStatusSkipped],
+		"moved":    s.counts[exportStatusMoved],
+		"removed":  removed,
+		"failures": s.failures,
+	})
+}
+
+type wikiSync struct {
+	client  *core.Client
+	config  *core.Config
+	state   *core.SyncState
+	options docxExportOptions
+
+	keep     map[string]bool // 知识库中仍然存在的文档
+	counts   map[string]int  // 各导出结果的文档数量
+	failures []gin.H
+	complete bool
+}
+
+// 依次导出节点及其子节点，子节点保存在以节点标题命名的目录中
+// 同级节点按照知识库目录中的位置依次导出，重名后缀和 {index} 不受并发获取子节点的影响
+func (s *wikiSync) walk(ctx context.Context, nodes []*core.WikiTreeNode, dir string) {
+	for i, node := range nodes {
+		// 快捷方式指向的文档在实体节点处导出
+		if node.NodeType == core.WikiNodeTypeShortcut {
+			continue
+		}
+		if node.Err != nil {
+			s.fail(node.WikiNode, fmt.Errorf("获取子节点失败: %w", node.Err))
+		}
+		if node.ObjType == "docx" {
+			s.keep[node.ObjToken] = true
+			options := s.options
+			options.Dir = dir
+			options.NodeToken = node.NodeToken
+			options.Index = i + 1
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				s.fail(node.WikiNode, err)
+			} else if result, err := exportDocx(ctx, s.client, s.config, s.state, node.ObjToken, options); err != nil {
+				s.fail(node.WikiNode, err)
+			} else {
+				s.counts[result.Status]++
+			}
+		} else {
+			log.Printf("跳过不支持增量同步的节点: %s (%s)", node.Title, node.ObjType)
+		}
+		if len(node.Children) > 0 {
+			s.walk(ctx, node.Children, s.options.Namer.dir(dir, node.Title, node.NodeToken))
+		}
+	}
+}
+
+func (s *wikiSync) fail(node *core.WikiNode, err error) {
+	log.Printf("同步节点 %s 失败: %s", node.Title, err)
+	s.failures = append(s.failures, gin.H{"node_token": node.NodeToken, "title": node.Title, "error": err.Error()})
+	s.complete = false
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 内置的网页界面，不需要单独安装桌面客户端
+//
+//go:embed templ/index.templ.html
+var indexHTML []byte
+
+// 注册网页界面的路由
+// webDir 不为空时改为提供目录中的静态文件，例如 make flutter-web 生成的 feishu2md_app/build/web
+func registerUI(router *gin.Engine, webDir string) {
+	if webDir == "" {
+		router.GET("/", func(c *gin.Context) {
+			c.Data(http.StatusOK, "text/html; charset=utf-8", indexHTML)
+		})
+		return
+	}
+
+	fileServer := http.FileServer(http.Dir(webDir))
+	router.NoRoute(func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		// 单页应用的前端路由都返回 index.html
+		file := filepath.Join(webDir, filepath.FromSlash(path.Clean("/"+c.Request.URL.Path)))
+		if _, err := os.Stat(file); err != nil {
+			c.File(filepath.Join(webDir, "index.html"))
+			return
+		}
+		fileServer.ServeHTTP(c.Writer, c.Request)
+	})
+}